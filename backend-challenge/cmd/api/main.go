@@ -2,24 +2,32 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"backend-challenge/internal/application/handler"
+	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/repository"
 	"backend-challenge/internal/domain/service"
 	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/breach"
 	grpcserver "backend-challenge/internal/infrastructure/grpc"
+	"backend-challenge/internal/infrastructure/mail"
 	"backend-challenge/internal/infrastructure/middleware"
 	repo "backend-challenge/internal/infrastructure/repository"
+	"backend-challenge/internal/infrastructure/storage"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -53,23 +61,246 @@ func main() {
 		defer mongoRepo.Disconnect(ctx)
 	}
 
+	// Share the MongoDB client with the other repositories
+	var mongoClient *mongo.Client
+	if mr, ok := mongoRepo.(*repo.MongoRepository); ok {
+		mongoClient = mr.Client
+	}
+
+	// Setup Refresh Token Repository
+	var refreshTokenRepo repository.RefreshTokenRepository
+	if mongoClient != nil {
+		refreshTokenRepo = repo.NewMongoRefreshTokenRepository(ctx, mongoClient, dbName)
+	} else {
+		refreshTokenRepo = repo.NewMockRefreshTokenRepository()
+	}
+
+	// Setup Token Revocation Repository
+	var revocationRepo repository.TokenRevocationRepository
+	if mongoClient != nil {
+		revocationRepo = repo.NewMongoTokenRevocationRepository(ctx, mongoClient, dbName)
+	} else {
+		revocationRepo = repo.NewMockTokenRevocationRepository()
+	}
+
+	// Setup Session Repository
+	var sessionRepo repository.SessionRepository
+	if mongoClient != nil {
+		sessionRepo = repo.NewMongoSessionRepository(ctx, mongoClient, dbName)
+	} else {
+		sessionRepo = repo.NewMockSessionRepository()
+	}
+
+	// Setup API Key Repository
+	var apiKeyRepo repository.APIKeyRepository
+	if mongoClient != nil {
+		apiKeyRepo = repo.NewMongoAPIKeyRepository(ctx, mongoClient, dbName)
+	} else {
+		apiKeyRepo = repo.NewMockAPIKeyRepository()
+	}
+
+	// Setup Audit Repository, falling back to a file when no database is available
+	var auditRepo repository.AuditRepository
+	if mongoClient != nil {
+		auditRepo = repo.NewMongoAuditRepository(ctx, mongoClient, dbName)
+	} else {
+		auditRepo, err = repo.NewFileAuditRepository(getEnv("AUDIT_LOG_FILE", "audit.log"))
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+	}
+
+	// Setup Audit Service
+	auditService := service.NewAuditService(auditRepo)
+
+	// Setup API Key Service
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, mongoRepo, service.WithAPIKeyAuditLog(auditService))
+
 	// Setup Auth Service
 	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
-	jwtExpiry := getEnvDuration("JWT_EXPIRY", 24*time.Hour) // Default 24 hours
-	authService := auth.NewJWTAuthService(jwtSecret, jwtExpiry)
+	jwtExpiry := getEnvDuration("JWT_EXPIRY", 15*time.Minute)                // Default 15 minutes
+	refreshExpiry := getEnvDuration("REFRESH_TOKEN_EXPIRY", 30*24*time.Hour) // Default 30 days
+	requireVerifiedEmail := getEnv("REQUIRE_EMAIL_VERIFICATION", "false") == "true"
+	authOptions := []auth.Option{
+		auth.WithRefreshTokens(refreshTokenRepo, mongoRepo, refreshExpiry),
+		auth.WithRevocationList(revocationRepo),
+		auth.WithSessions(sessionRepo),
+		auth.WithAPIKeys(apiKeyService),
+		auth.WithAuditLog(auditService),
+		auth.WithRequiredEmailVerification(requireVerifiedEmail),
+	}
+
+	// Sign with an asymmetric key instead of the shared secret when configured
+	if keyFile := getEnv("JWT_SIGNING_KEY_FILE", ""); keyFile != "" {
+		keySet, err := auth.LoadKeySet(
+			keyFile,
+			getEnv("JWT_KEY_ID", ""),
+			strings.Split(getEnv("JWT_PREVIOUS_KEY_FILES", ""), ","),
+		)
+		if err != nil {
+			log.Fatalf("Failed to load JWT signing keys: %v", err)
+		}
+		authOptions = append(authOptions, auth.WithKeySet(keySet))
+		log.Println("Signing JWTs with asymmetric key from", keyFile)
+	}
+
+	authService := auth.NewJWTAuthService(jwtSecret, jwtExpiry, authOptions...)
+
+	// Setup One-Time Token Repository
+	var oneTimeTokenRepo repository.OneTimeTokenRepository
+	if mongoClient != nil {
+		oneTimeTokenRepo = repo.NewMongoOneTimeTokenRepository(ctx, mongoClient, dbName)
+	} else {
+		oneTimeTokenRepo = repo.NewMockOneTimeTokenRepository()
+	}
+
+	// Setup Mailer, writing emails to files or the log since no SMTP server is required
+	var mailer service.Mailer = mail.NewLogMailer()
+	if mailDir := getEnv("MAIL_DIR", ""); mailDir != "" {
+		fileMailer, err := mail.NewFileMailer(mailDir)
+		if err != nil {
+			log.Fatalf("Failed to setup mailer: %v", err)
+		}
+		mailer = fileMailer
+	}
+
+	// Setup Login Attempt Repository
+	var loginAttemptRepo repository.LoginAttemptRepository
+	if mongoClient != nil {
+		loginAttemptRepo = repo.NewMongoLoginAttemptRepository(ctx, mongoClient, dbName)
+	} else {
+		loginAttemptRepo = repo.NewMockLoginAttemptRepository()
+	}
+
+	// Setup Login Throttling
+	throttleConfig := service.DefaultLoginThrottleConfig()
+	throttleConfig.EmailFreeAttempts = getEnvInt("LOGIN_MAX_ATTEMPTS_PER_EMAIL", throttleConfig.EmailFreeAttempts)
+	throttleConfig.IPFreeAttempts = getEnvInt("LOGIN_MAX_ATTEMPTS_PER_IP", throttleConfig.IPFreeAttempts)
+	throttleConfig.MaxDelay = getEnvDuration("LOGIN_MAX_LOCKOUT", throttleConfig.MaxDelay)
+	loginThrottler := service.NewLoginThrottler(loginAttemptRepo, throttleConfig)
+
+	// Setup Password Policy
+	passwordConfig := service.DefaultPasswordPolicyConfig()
+	passwordConfig.MinLength = getEnvInt("PASSWORD_MIN_LENGTH", passwordConfig.MinLength)
+	passwordConfig.RequireUppercase = getEnvBool("PASSWORD_REQUIRE_UPPERCASE", passwordConfig.RequireUppercase)
+	passwordConfig.RequireLowercase = getEnvBool("PASSWORD_REQUIRE_LOWERCASE", passwordConfig.RequireLowercase)
+	passwordConfig.RequireDigit = getEnvBool("PASSWORD_REQUIRE_DIGIT", passwordConfig.RequireDigit)
+	passwordConfig.RequireSymbol = getEnvBool("PASSWORD_REQUIRE_SYMBOL", passwordConfig.RequireSymbol)
+	passwordConfig.BanUserInfo = getEnvBool("PASSWORD_BAN_USER_INFO", passwordConfig.BanUserInfo)
+	if words := getEnv("PASSWORD_BANNED_WORDS", ""); words != "" {
+		passwordConfig.BannedWords = strings.Split(words, ",")
+	}
+
+	// Check new passwords against a local breached password list when configured
+	var breachedPasswords service.BreachedPasswordChecker
+	if breachedDir := getEnv("BREACHED_PASSWORDS_DIR", ""); breachedDir != "" {
+		checker, err := breach.NewFileChecker(breachedDir)
+		if err != nil {
+			log.Fatalf("Failed to setup breached password check: %v", err)
+		}
+		breachedPasswords = checker
+	}
+	passwordPolicy := service.NewPasswordPolicy(passwordConfig, breachedPasswords)
+
+	// Setup Password Hashing, existing hashes are upgraded on login
+	var passwordHasher model.PasswordHasher
+	switch algorithm := getEnv("PASSWORD_HASH_ALGORITHM", model.PasswordHashBcrypt); algorithm {
+	case model.PasswordHashBcrypt:
+		passwordHasher = model.NewBcryptHasher(getEnvInt("BCRYPT_COST", bcrypt.DefaultCost))
+	case model.PasswordHashArgon2id:
+		argonParams := model.DefaultArgon2idParams()
+		argonParams.Memory = uint32(getEnvInt("ARGON2_MEMORY_KIB", int(argonParams.Memory)))
+		argonParams.Iterations = uint32(getEnvInt("ARGON2_ITERATIONS", int(argonParams.Iterations)))
+		argonParams.Parallelism = uint8(getEnvInt("ARGON2_PARALLELISM", int(argonParams.Parallelism)))
+		passwordHasher = model.NewArgon2idHasher(argonParams)
+	default:
+		log.Fatalf("Unsupported PASSWORD_HASH_ALGORITHM %q", algorithm)
+	}
+
+	// Setup Avatar Storage on the local filesystem
+	avatarStore, err := storage.NewFileBlobStore(getEnv("AVATAR_DIR", "data/avatars"))
+	if err != nil {
+		log.Fatalf("Failed to setup avatar storage: %v", err)
+	}
 
 	// Setup User Service
-	userService := service.NewUserService(mongoRepo)
-	
+	userService := service.NewUserService(
+		mongoRepo,
+		service.WithPasswordPolicy(passwordPolicy),
+		service.WithPasswordHasher(passwordHasher),
+		service.WithEmailTokens(oneTimeTokenRepo, mailer, getEnv("APP_URL", "http://localhost:8080")),
+		service.WithPasswordResetTTL(getEnvDuration("PASSWORD_RESET_EXPIRY", time.Hour)),
+		service.WithEmailVerification(
+			requireVerifiedEmail,
+			getEnvDuration("EMAIL_VERIFICATION_EXPIRY", 24*time.Hour),
+		),
+		service.WithLoginThrottle(loginThrottler),
+		service.WithMFAIssuer(getEnv("MFA_ISSUER", "backend-challenge")),
+		service.WithAuditLog(auditService),
+		service.WithAvatarStore(avatarStore),
+		service.WithTokenRevoker(authService),
+	)
+
+	// Grant the admin role to the configured accounts
+	bootstrapAdmins(ctx, mongoRepo, userService, getEnv("ADMIN_EMAILS", ""))
+
 	// Setup Todo Repository
-	var mongoClient *mongo.Client
-	if mr, ok := mongoRepo.(*repo.MongoRepository); ok {
-		mongoClient = mr.Client
+	var todoRepo repository.TodoRepository
+	if mongoClient != nil {
+		todoRepo, err = repo.NewMongoTodoRepository(ctx, mongoClient, dbName)
+		if err != nil {
+			log.Fatalf("Failed to setup todo repository: %v", err)
+		}
+	} else {
+		todoRepo = repo.NewMockTodoRepository()
 	}
-	todoRepo := repo.NewMongoTodoRepository(mongoClient, dbName)
+
+	// Setup Privacy Job Repository
+	var privacyJobRepo repository.PrivacyJobRepository
+	if mongoClient != nil {
+		privacyJobRepo = repo.NewMongoPrivacyJobRepository(ctx, mongoClient, dbName)
+	} else {
+		privacyJobRepo = repo.NewMockPrivacyJobRepository()
+	}
+
+	// Setup Privacy Service for data export and erasure requests
+	privacyService := service.NewPrivacyService(
+		privacyJobRepo,
+		service.PersonalDataStores{
+			Users:         mongoRepo,
+			Sessions:      sessionRepo,
+			APIKeys:       apiKeyRepo,
+			RefreshTokens: refreshTokenRepo,
+			OneTimeTokens: oneTimeTokenRepo,
+			Revocations:   revocationRepo,
+			Audit:         auditRepo,
+			Avatars:       avatarStore,
+			Todos:         todoRepo,
+		},
+		auditService,
+		getEnvDuration("PRIVACY_EXPORT_RETENTION", 7*24*time.Hour), // Default 7 days
+	)
 	
-	// Setup Todo Service
-	todoService := service.NewTodoService(todoRepo)
+	// Setup the scheduler that returns clicked todo items to the main list
+	todoReturnScheduler := service.NewTodoReturnScheduler(todoRepo)
+
+	// Setup Todo Service with the time clicked items stay in their type column
+	returnDelaysByType, err := getTodoReturnDelaysByType(getEnv("TODO_RETURN_AFTER_BY_TYPE", ""))
+	if err != nil {
+		log.Fatalf("Invalid TODO_RETURN_AFTER_BY_TYPE: %v", err)
+	}
+	returnDelays := service.ReturnDelays{
+		Default: getEnvDuration("TODO_RETURN_AFTER", model.DefaultReturnAfter),
+		ByType:  returnDelaysByType,
+	}
+	if err := returnDelays.Validate(); err != nil {
+		log.Fatalf("Invalid todo return delays: %v", err)
+	}
+	todoService := service.NewTodoService(
+		todoRepo,
+		service.WithReturnScheduler(todoReturnScheduler),
+		service.WithReturnDelays(returnDelays),
+	)
 	
 	// Temporarily comment out external repository
 	// Setup External User Repository
@@ -79,7 +310,7 @@ func main() {
 	transformService := service.NewTransformService(nil)
 
 	// Setup REST API server
-	restServer := setupRESTServer(userService, authService, transformService, todoService)
+	restServer := setupRESTServer(userService, authService, apiKeyService, auditService, privacyService, transformService, todoService)
 	
 	// Setup gRPC server
 	grpcServer := setupGRPCServer(userService, authService, transformService)
@@ -87,8 +318,19 @@ func main() {
 	// Start background user count logging
 	go startBackgroundUserCount(ctx, mongoRepo)
 	
-	// Start background todo item return checker
-	go startBackgroundTodoReturn(ctx, todoService)
+	// Start returning clicked todo items when they are due
+	go startTodoReturnScheduler(ctx, todoReturnScheduler)
+
+	// Start background purge of deleted users
+	go startBackgroundUserPurge(
+		ctx,
+		privacyService,
+		getEnvDuration("USER_DELETE_RETENTION", 30*24*time.Hour), // Default 30 days
+		getEnvDuration("USER_PURGE_INTERVAL", time.Hour),
+	)
+
+	// Start background data export and erasure jobs
+	go startBackgroundPrivacyJobs(ctx, privacyService, getEnvDuration("PRIVACY_JOB_INTERVAL", 5*time.Second))
 
 	// Start both REST and gRPC servers
 	go startRESTServer(restServer)
@@ -99,16 +341,21 @@ func main() {
 }
 
 // Setup REST API server
-func setupRESTServer(userService service.UserService, authService auth.AuthService, transformService service.TransformService, todoService service.TodoService) *http.Server {
+func setupRESTServer(userService service.UserService, authService auth.AuthService, apiKeyService service.APIKeyService, auditService service.AuditService, privacyService service.PrivacyService, transformService service.TransformService, todoService service.TodoService) *http.Server {
 	// Setup Router
 	r := mux.NewRouter()
 	r.Use(middleware.LoggingMiddleware)
 	r.Use(middleware.PanicRecoveryMiddleware)
+	r.Use(middleware.ClientInfoMiddleware(getEnv("TRUST_PROXY_HEADERS", "false") == "true"))
 
 	// Register handlers
 	handler.RegisterHealthHandler(r) // Add health check handler
 	handler.RegisterAuthHandler(r, authService, userService)
+	handler.RegisterJWKSHandler(r, authService)
+	handler.RegisterAPIKeyHandler(r, apiKeyService, authService)
 	handler.RegisterUserHandler(r, userService, authService)
+	handler.RegisterPrivacyHandler(r, privacyService, authService)
+	handler.RegisterAuditHandler(r, auditService, authService)
 	handler.RegisterTransformHandler(r, transformService)
 	handler.RegisterTodoHandler(r, todoService, authService)
 
@@ -132,8 +379,10 @@ func startRESTServer(srv *http.Server) {
 
 // Setup gRPC server
 func setupGRPCServer(userService service.UserService, authService auth.AuthService, transformService service.TransformService) *grpc.Server {
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Create gRPC server with token validation for protected methods
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcserver.UnaryAuthInterceptor(authService)),
+	)
 
 	// Register services
 	grpcserver.Register(grpcServer, userService, authService)
@@ -160,6 +409,31 @@ func startGRPCServer(grpcServer *grpc.Server) {
 	}
 }
 
+// Promote existing users listed in a comma separated list of emails to admins
+func bootstrapAdmins(ctx context.Context, userRepo repository.UserRepository, userService service.UserService, emails string) {
+	for _, email := range strings.Split(emails, ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+
+		user, err := userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			log.Printf("Admin bootstrap: user %s not found, register it and restart to grant admin", email)
+			continue
+		}
+		if user.IsAdmin() {
+			continue
+		}
+
+		if _, err := userService.ChangeRole(ctx, user.ID, model.RoleAdmin); err != nil {
+			log.Printf("Admin bootstrap: failed to promote %s: %v", email, err)
+			continue
+		}
+		log.Printf("Admin bootstrap: granted admin role to %s", email)
+	}
+}
+
 // Background goroutine that logs the number of users every 10 seconds
 func startBackgroundUserCount(ctx context.Context, userRepo repository.UserRepository) {
 	ticker := time.NewTicker(10 * time.Second)
@@ -181,23 +455,62 @@ func startBackgroundUserCount(ctx context.Context, userRepo repository.UserRepos
 	}
 }
 
-// Background goroutine that checks and returns todo items that have reached their return time
-func startBackgroundTodoReturn(ctx context.Context, todoService service.TodoService) {
-	ticker := time.NewTicker(1 * time.Second)
+// Background goroutine that permanently removes users deleted longer than the retention period ago
+func startBackgroundUserPurge(ctx context.Context, privacyService service.PrivacyService, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			count, err := privacyService.PurgeDeletedUsers(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Error purging deleted users: %v", err)
+			} else if count > 0 {
+				log.Printf("Purged %d deleted users", count)
+			}
+		case <-ctx.Done():
+			log.Println("Stopping background user purge")
+			return
+		}
+	}
+}
+
+// Background goroutine that runs queued data export and erasure jobs
+func startBackgroundPrivacyJobs(ctx context.Context, privacyService service.PrivacyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			now := time.Now().Format(time.RFC3339)
-			count, err := todoService.ReturnTimedOutItems(ctx, now)
+			count, err := privacyService.RunPendingJobs(ctx)
 			if err != nil {
-				log.Printf("Error returning timed out todo items: %v", err)
+				log.Printf("Error running privacy jobs: %v", err)
 			} else if count > 0 {
-				log.Printf("Returned %d timed out todo items", count)
+				log.Printf("Ran %d privacy jobs", count)
 			}
 		case <-ctx.Done():
-			log.Println("Stopping background todo return checker")
+			log.Println("Stopping background privacy jobs")
+			return
+		}
+	}
+}
+
+// Background goroutine that returns todo items when they reach their return time
+func startTodoReturnScheduler(ctx context.Context, scheduler service.TodoReturnScheduler) {
+	for {
+		// Run only fails when the scheduled items cannot be loaded, so try again
+		err := scheduler.Run(ctx)
+		if err == nil {
+			log.Println("Stopping todo return scheduler")
+			return
+		}
+		log.Printf("Error starting todo return scheduler: %v", err)
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -240,6 +553,49 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// Helper to get integer from environment variable with fallback
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("Invalid integer for %s, using default", key)
+	}
+	return fallback
+}
+
+// Helper to get boolean from environment variable with fallback
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("Invalid boolean for %s, using default", key)
+	}
+	return fallback
+}
+
+// getTodoReturnDelaysByType parses return delays per item type, e.g. "Fruit=10s,Vegetable=1m".
+// The types and delays are checked by ReturnDelays.Validate.
+func getTodoReturnDelaysByType(value string) (map[model.ItemType]time.Duration, error) {
+	delays := make(map[model.ItemType]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid entry %q, expected Type=duration", entry)
+		}
+		delay, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in %q: %w", entry, err)
+		}
+		delays[model.ItemType(strings.TrimSpace(parts[0]))] = delay
+	}
+	return delays, nil
+}
+
 // Helper to get duration from environment variable with fallback
 func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	if value, exists := os.LookupEnv(key); exists {
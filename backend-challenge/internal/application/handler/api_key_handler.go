@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler handles API key management requests
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+// RegisterAPIKeyHandler registers API key routes
+func RegisterAPIKeyHandler(r *mux.Router, apiKeyService service.APIKeyService, authService auth.AuthService) {
+	handler := &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+
+	// Define protected routes
+	protected := r.PathPrefix("/api/auth/api-keys").Subrouter()
+	// API keys cannot manage API keys, so that a leaked key cannot be used
+	// to create new ones
+	protected.Use(middleware.AuthMiddleware(authService))
+
+	// Register routes
+	protected.HandleFunc("", handler.ListAPIKeys).Methods("GET")
+	protected.HandleFunc("", handler.CreateAPIKey).Methods("POST")
+	protected.HandleFunc("/{id}", handler.RevokeAPIKey).Methods("DELETE")
+}
+
+// ListAPIKeys handles the request to list the API keys of the authenticated user
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyService.List(r.Context(), middleware.PrincipalFromRequest(r).UserID)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, keys, http.StatusOK)
+}
+
+// CreateAPIKey handles the request to create an API key
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.CreateAPIKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Create API key
+	created, err := h.apiKeyService.Create(r.Context(), middleware.PrincipalFromRequest(r).UserID, &input)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// The key is only returned once
+	respondWithJSON(w, created, http.StatusCreated)
+}
+
+// RevokeAPIKey handles the request to revoke an API key
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get API key ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Revoke API key
+	if err := h.apiKeyService.Revoke(r.Context(), middleware.PrincipalFromRequest(r).UserID, id); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "API key revoked successfully",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
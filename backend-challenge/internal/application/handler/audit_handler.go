@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
+	"github.com/gorilla/mux"
+)
+
+// AuditHandler handles audit log requests
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// RegisterAuditHandler registers audit log routes
+func RegisterAuditHandler(r *mux.Router, auditService service.AuditService, authService auth.AuthService) {
+	handler := &AuditHandler{
+		auditService: auditService,
+	}
+
+	// Define protected routes
+	protected := r.PathPrefix("/api/audit").Subrouter()
+	// Only admins may read the audit log. API keys have no scope for it.
+	protected.Use(middleware.AuthMiddleware(authService, middleware.RequireRole(model.RoleAdmin)))
+
+	// Register routes
+	protected.HandleFunc("", handler.ListEvents).Methods("GET")
+}
+
+// ListEvents handles the request to list audit events, filtered by action, actor,
+// target and time range (RFC 3339)
+func (h *AuditHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := &model.AuditFilter{
+		Action:   model.AuditAction(query.Get("action")),
+		ActorID:  query.Get("actor"),
+		TargetID: query.Get("target"),
+	}
+
+	// Parse time range
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			respondWithError(w, errors.New("from must be an RFC 3339 time"), http.StatusBadRequest)
+			return
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			respondWithError(w, errors.New("to must be an RFC 3339 time"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Parse pagination, invalid values fall back to the defaults
+	filter.Page, _ = strconv.Atoi(query.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(query.Get("pageSize"))
+
+	// Get events
+	events, total, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Create response
+	response := ResponseWithPagination{
+		Data:       events,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalItems: &total,
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
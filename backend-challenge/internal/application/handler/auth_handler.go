@@ -2,11 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/service"
 	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
 	"github.com/gorilla/mux"
 )
 
@@ -29,6 +33,25 @@ func RegisterAuthHandler(r *mux.Router, authService auth.AuthService, userServic
 	// Register routes
 	authRouter.HandleFunc("/register", handler.Register).Methods("POST")
 	authRouter.HandleFunc("/login", handler.Login).Methods("POST")
+	authRouter.HandleFunc("/login/mfa", handler.LoginMFA).Methods("POST")
+	authRouter.HandleFunc("/refresh", handler.Refresh).Methods("POST")
+	authRouter.HandleFunc("/forgot-password", handler.ForgotPassword).Methods("POST")
+	authRouter.HandleFunc("/reset-password", handler.ResetPassword).Methods("POST")
+	authRouter.HandleFunc("/verify", handler.VerifyEmail).Methods("GET")
+	authRouter.HandleFunc("/resend-verification", handler.ResendVerification).Methods("POST")
+
+	// Define protected routes. API keys cannot sign out or manage sessions and
+	// two-factor authentication, so these require a bearer token.
+	protected := authRouter.NewRoute().Subrouter()
+	protected.Use(middleware.AuthMiddleware(authService))
+
+	protected.HandleFunc("/logout", handler.Logout).Methods("POST")
+	protected.HandleFunc("/logout-all", handler.LogoutAll).Methods("POST")
+	protected.HandleFunc("/mfa/enroll", handler.EnrollMFA).Methods("POST")
+	protected.HandleFunc("/mfa/confirm", handler.ConfirmMFA).Methods("POST")
+	protected.HandleFunc("/mfa/disable", handler.DisableMFA).Methods("POST")
+	protected.HandleFunc("/sessions", handler.ListSessions).Methods("GET")
+	protected.HandleFunc("/sessions/{id}", handler.RevokeSession).Methods("DELETE")
 }
 
 // Register handles user registration
@@ -47,18 +70,24 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate token
-	token, err := h.authService.GenerateToken(user)
+	// Generate access and refresh tokens. Users who have to verify their email
+	// first get them when they log in afterwards.
+	pair, err := h.authService.GenerateTokenPair(r.Context(), user)
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		response := SuccessResponse{
+			Message: "Registered successfully, verify your email before logging in",
+			Data:    user,
+		}
+		respondWithJSON(w, response, http.StatusCreated)
+		return
+	}
 	if err != nil {
 		respondWithError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	// Create response
-	responseData := TokenResponse{
-		Token: token,
-		User:  user,
-	}
+	responseData := newTokenResponse(pair, user)
 
 	respondWithJSON(w, responseData, http.StatusCreated)
 }
@@ -75,22 +104,339 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Authenticate user
 	user, err := h.userService.Login(r.Context(), &input)
 	if err != nil {
-		respondWithDomainError(w, err)
+		respondWithLoginError(w, err)
 		return
 	}
 
-	// Generate token
-	token, err := h.authService.GenerateToken(user)
+	// Users with two-factor authentication get a token for the second step instead
+	if user.MFA.Enabled {
+		mfaToken, expiresIn, err := h.authService.GenerateMFAToken(user)
+		if err != nil {
+			respondWithError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		response := MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(expiresIn.Seconds()),
+		}
+
+		respondWithJSON(w, response, http.StatusOK)
+		return
+	}
+
+	// Generate access and refresh tokens
+	pair, err := h.authService.GenerateTokenPair(r.Context(), user)
 	if err != nil {
 		respondWithError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	// Create response
-	responseData := TokenResponse{
-		Token: token,
-		User:  user,
-	}
+	responseData := newTokenResponse(pair, user)
 
 	respondWithJSON(w, responseData, http.StatusOK)
-}
\ No newline at end of file
+}
+
+// LoginMFA completes a login with a second factor
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.MFALoginInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Validate the token from the password step
+	claims, err := h.authService.ValidateMFAToken(r.Context(), input.MFAToken)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// The MFA token can only be used once, whether or not the code is right
+	if err := h.authService.RevokeToken(r.Context(), claims); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Check the second factor
+	user, err := h.userService.VerifyMFA(r.Context(), claims.UserID, input.Code)
+	if err != nil {
+		respondWithLoginError(w, err)
+		return
+	}
+
+	// Generate access and refresh tokens
+	pair, err := h.authService.GenerateTokenPair(r.Context(), user)
+	if err != nil {
+		respondWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, newTokenResponse(pair, user), http.StatusOK)
+}
+
+// Refresh exchanges a refresh token for a new token pair
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.RefreshTokenInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Rotate refresh token
+	pair, user, err := h.authService.RefreshTokens(r.Context(), input.RefreshToken)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, newTokenResponse(pair, user), http.StatusOK)
+}
+
+// Logout revokes the access token used for the request and its refresh tokens
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Revoke token
+	if err := h.authService.RevokePrincipalToken(r.Context(), principal); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Logged out successfully",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// LogoutAll revokes every token of the authenticated user on all devices
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Revoke all tokens of the user
+	if err := h.authService.RevokeAllForUser(r.Context(), principal.UserID); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Logged out from all devices successfully",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// ForgotPassword emails a password reset link to the user
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.ForgotPasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Request reset
+	if err := h.userService.RequestPasswordReset(r.Context(), input.Email); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Same response whether or not the email exists
+	response := SuccessResponse{
+		Message: "If the email is registered, a password reset link has been sent",
+	}
+
+	respondWithJSON(w, response, http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.ResetPasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Reset password
+	user, err := h.userService.ResetPassword(r.Context(), &input)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Tokens issued with the old password must not stay valid
+	if err := h.authService.RevokeAllForUser(r.Context(), user.ID); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Password has been reset successfully",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// VerifyEmail confirms the email address of a user from the emailed link
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithDomainError(w, service.ErrInvalidVerificationToken)
+		return
+	}
+
+	// Verify email
+	user, err := h.userService.VerifyEmail(r.Context(), token)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Email verified successfully",
+		Data:    user,
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// ResendVerification emails a new verification link to an unverified user
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var input model.ResendVerificationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Resend verification
+	if err := h.userService.ResendVerification(r.Context(), input.Email); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Same response whether or not the email exists or is verified
+	response := SuccessResponse{
+		Message: "If the email is registered and not yet verified, a verification link has been sent",
+	}
+
+	respondWithJSON(w, response, http.StatusAccepted)
+}
+
+// EnrollMFA starts two-factor enrollment for the authenticated user
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Generate secret
+	enrollment, err := h.userService.EnrollMFA(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, enrollment, http.StatusOK)
+}
+
+// ConfirmMFA enables two-factor authentication with a code from the authenticator app
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Parse request body
+	var input model.MFACodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Enable two-factor authentication
+	codes, err := h.userService.ConfirmMFA(r.Context(), principal.UserID, input.Code)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// Recovery codes are only shown once
+	respondWithJSON(w, MFAEnrollmentResponse{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// DisableMFA turns off two-factor authentication for the authenticated user
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Parse request body
+	var input model.MFADisableInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Disable two-factor authentication
+	if err := h.userService.DisableMFA(r.Context(), principal.UserID, &input); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Two-factor authentication disabled",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// ListSessions lists the devices the authenticated user is signed in on
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Get sessions
+	sessions, err := h.authService.ListSessions(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			Session: session,
+			Current: session.ID == principal.SessionID,
+		})
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// RevokeSession signs the authenticated user out of one of their sessions
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal := middleware.PrincipalFromRequest(r)
+
+	// Get session ID from URL
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	// Revoke session
+	if err := h.authService.RevokeSession(r.Context(), principal.UserID, sessionID); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Session signed out successfully",
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// respondWithLoginError writes a login error, telling locked out clients when they may try again
+func respondWithLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	}
+	respondWithDomainError(w, err)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"backend-challenge/internal/infrastructure/auth"
+	"github.com/gorilla/mux"
+)
+
+// JWKSHandler publishes the public keys used to sign tokens
+type JWKSHandler struct {
+	authService auth.AuthService
+}
+
+// RegisterJWKSHandler registers the JWKS route
+func RegisterJWKSHandler(r *mux.Router, authService auth.AuthService) {
+	handler := &JWKSHandler{
+		authService: authService,
+	}
+
+	// Open route so that other services can verify tokens without the secret
+	r.HandleFunc("/.well-known/jwks.json", handler.GetJWKS).Methods("GET")
+}
+
+// GetJWKS handles requests for the JSON Web Key Set
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	// Keys only change on restart, let verifiers cache them for a while
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, h.authService.JWKS(), http.StatusOK)
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
+	"github.com/gorilla/mux"
+)
+
+// PrivacyHandler handles personal data export and erasure requests
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+	policy         service.UserPolicy
+}
+
+// RegisterPrivacyHandler registers personal data export and erasure routes
+func RegisterPrivacyHandler(r *mux.Router, privacyService service.PrivacyService, authService auth.AuthService) {
+	handler := &PrivacyHandler{
+		privacyService: privacyService,
+		policy:         service.NewUserPolicy(),
+	}
+
+	// Define protected routes
+	protected := r.PathPrefix("/api/users").Subrouter()
+	// API keys cannot export or erase personal data, so that a leaked key
+	// cannot be used to copy or destroy an account
+	protected.Use(middleware.AuthMiddleware(authService))
+
+	// Register routes
+	protected.HandleFunc("/{id}/export", handler.RequestExport).Methods("POST")
+	protected.HandleFunc("/{id}/export", handler.GetExport).Methods("GET")
+	protected.HandleFunc("/{id}/erasure", handler.RequestErasure).Methods("POST")
+	protected.HandleFunc("/{id}/jobs/{jobId}", handler.GetJob).Methods("GET")
+}
+
+// RequestExport handles the request to export a user's personal data
+func (h *PrivacyHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may export this user's data
+	if !h.policy.CanExportData(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Queue the export
+	job, err := h.privacyService.RequestExport(r.Context(), id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJob(w, job)
+}
+
+// GetExport handles the request to download a user's latest data export. The archive
+// is returned once the export has completed, and the job status until then.
+func (h *PrivacyHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may export this user's data
+	if !h.policy.CanExportData(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Get the latest export
+	job, err := h.privacyService.LatestExport(r.Context(), id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+	if job.Status != model.PrivacyJobCompleted || job.Archive == nil {
+		respondWithJob(w, job)
+		return
+	}
+
+	// Send the archive as a download
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, id))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job.Archive); err != nil {
+		log.Printf("Data export download failed: %v", err)
+	}
+}
+
+// RequestErasure handles the request to erase a user and their personal data
+func (h *PrivacyHandler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may erase this user
+	if !h.policy.CanErase(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Queue the erasure
+	job, err := h.privacyService.RequestErasure(r.Context(), id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJob(w, job)
+}
+
+// GetJob handles the request to get the status of an export or erasure job
+func (h *PrivacyHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	// Get user and job ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Anyone who may request a job may follow it
+	actor := middleware.PrincipalFromRequest(r)
+	if !h.policy.CanExportData(actor, id) && !h.policy.CanErase(actor, id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	job, err := h.privacyService.GetJob(r.Context(), id, vars["jobId"])
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, job, http.StatusOK)
+}
+
+// respondWithJob responds with the status of a job, as accepted while it has not finished
+// and with a link to follow it
+func respondWithJob(w http.ResponseWriter, job *model.PrivacyJob) {
+	status := http.StatusOK
+	if !job.IsDone() {
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/users/%s/jobs/%s", job.UserID, job.ID))
+	respondWithJSON(w, job, status)
+}
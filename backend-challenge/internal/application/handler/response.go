@@ -5,9 +5,11 @@ import (
 	"errors"
 	"net/http"
 
+	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/service"
 	"backend-challenge/internal/infrastructure/auth"
 	repo "backend-challenge/internal/infrastructure/repository"
+	"backend-challenge/pkg/cursor"
 )
 
 // ErrorResponse represents an error response
@@ -21,18 +23,40 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// ResponseWithPagination represents a response with pagination
+// ResponseWithPagination represents a response with pagination. Page is left out
+// for cursor pagination, and NextCursor when there is no page after this one.
 type ResponseWithPagination struct {
 	Data       interface{} `json:"data"`
-	Page       int         `json:"page"`
+	Page       int         `json:"page,omitempty"`
 	PageSize   int         `json:"pageSize"`
-	TotalItems int64       `json:"totalItems"`
+	TotalItems *int64      `json:"totalItems,omitempty"` // Left out for cursor pages
+	NextCursor string      `json:"nextCursor,omitempty"`
 }
 
 // TokenResponse represents a token response
 type TokenResponse struct {
-	Token string `json:"token"`
-	User  interface{} `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refreshToken,omitempty"`
+	ExpiresIn    int64       `json:"expiresIn,omitempty"`
+	User         interface{} `json:"user"`
+}
+
+// MFAChallengeResponse is returned by login when the user must enter a second factor
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
+
+// MFAEnrollmentResponse is returned when two-factor authentication is confirmed
+type MFAEnrollmentResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// SessionResponse represents a login session, marking the session of the current request
+type SessionResponse struct {
+	*model.Session
+	Current bool `json:"current"`
 }
 
 // mapErrorToHTTPStatus maps domain errors to HTTP status codes
@@ -42,18 +66,82 @@ func mapErrorToHTTPStatus(err error) int {
 		return http.StatusNotFound
 	case errors.Is(err, service.ErrEmailExists), errors.Is(err, repo.ErrDuplicateEmail):
 		return http.StatusConflict
-	case errors.Is(err, service.ErrInvalidID):
+	case errors.Is(err, service.ErrInvalidID), errors.Is(err, service.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidSortField), errors.Is(err, service.ErrInvalidSortOrder),
+		errors.Is(err, service.ErrCursorSortField), errors.Is(err, cursor.ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrWeakPassword), errors.Is(err, service.ErrInvalidResetToken),
+		errors.Is(err, service.ErrInvalidVerificationToken):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTooManyImportRows):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrInvalidDisplayName), errors.Is(err, service.ErrInvalidLocale),
+		errors.Is(err, service.ErrInvalidTimezone), errors.Is(err, service.ErrInvalidBio):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAvatarNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAvatar):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUnsupportedAvatarType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrEmailNotVerified):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrTooManyLoginAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrMFANotEnabled), errors.Is(err, service.ErrMFANotEnrolled):
 		return http.StatusBadRequest
-	case errors.Is(err, service.ErrInvalidPassword):
+	case errors.Is(err, service.ErrInvalidMFACode):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidScope), errors.Is(err, service.ErrInvalidAPIKeyName),
+		errors.Is(err, service.ErrInvalidAPIKeyExpiry), errors.Is(err, service.ErrTooManyAPIKeys):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInsufficientScope):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPrivacyJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrTodoNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidReturnAfter), errors.Is(err, service.ErrInvalidTodoType):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTodoAlreadyInMain), errors.Is(err, service.ErrTodoConflict):
+		return http.StatusConflict
+	case errors.Is(err, auth.ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, auth.ErrInvalidAPIKey):
 		return http.StatusUnauthorized
 	case errors.Is(err, auth.ErrMissingToken), errors.Is(err, auth.ErrInvalidToken), 
-		errors.Is(err, auth.ErrTokenExpired), errors.Is(err, auth.ErrInvalidSignature):
+		errors.Is(err, auth.ErrTokenExpired), errors.Is(err, auth.ErrInvalidSignature),
+		errors.Is(err, auth.ErrTokenRevoked):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused):
 		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrRefreshNotEnabled), errors.Is(err, service.ErrMailNotConfigured),
+		errors.Is(err, service.ErrAvatarsNotConfigured):
+		return http.StatusNotImplemented
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
+// newTokenResponse creates a token response from a token pair
+func newTokenResponse(pair *auth.TokenPair, user interface{}) TokenResponse {
+	return TokenResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+		User:         user,
+	}
+}
+
 // respondWithError writes an error response
 func respondWithError(w http.ResponseWriter, err error, status int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -7,6 +7,7 @@ import (
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/service"
 	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
 	"github.com/gorilla/mux"
 )
 
@@ -23,7 +24,7 @@ func RegisterTodoHandler(r *mux.Router, todoService service.TodoService, authSer
 
 	// Define protected routes
 	protected := r.PathPrefix("/api/todos").Subrouter()
-	protected.Use(createAuthMiddleware(authService))
+	protected.Use(middleware.AuthMiddleware(authService, middleware.AllowAPIKeys(model.ScopeTodosRead, model.ScopeTodosWrite)))
 
 	// Register routes
 	protected.HandleFunc("", handler.ListTodos).Methods("GET")
@@ -32,12 +33,13 @@ func RegisterTodoHandler(r *mux.Router, todoService service.TodoService, authSer
 	protected.HandleFunc("/{id}", handler.UpdateTodo).Methods("PUT")
 	protected.HandleFunc("/{id}", handler.DeleteTodo).Methods("DELETE")
 	protected.HandleFunc("/{id}/click", handler.ClickTodo).Methods("POST")
+	protected.HandleFunc("/{id}/return", handler.ReturnTodo).Methods("POST")
 }
 
-// ListTodos handles the request to list all todos grouped by status and type
+// ListTodos handles the request to list the authenticated user's todos grouped by status and type
 func (h *TodoHandler) ListTodos(w http.ResponseWriter, r *http.Request) {
 	// Get todos
-	todos, err := h.todoService.List(r.Context())
+	todos, err := h.todoService.List(r.Context(), middleware.PrincipalFromRequest(r).UserID)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -56,7 +58,7 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create todo
-	todo, err := h.todoService.Create(r.Context(), &input)
+	todo, err := h.todoService.Create(r.Context(), middleware.PrincipalFromRequest(r).UserID, &input)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -72,7 +74,7 @@ func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Get todo
-	todo, err := h.todoService.GetByID(r.Context(), id)
+	todo, err := h.todoService.GetByID(r.Context(), middleware.PrincipalFromRequest(r).UserID, id)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -95,7 +97,7 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update todo
-	todo, err := h.todoService.Update(r.Context(), id, &input)
+	todo, err := h.todoService.Update(r.Context(), middleware.PrincipalFromRequest(r).UserID, id, &input)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -111,7 +113,7 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Delete todo
-	err := h.todoService.Delete(r.Context(), id)
+	err := h.todoService.Delete(r.Context(), middleware.PrincipalFromRequest(r).UserID, id)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -132,7 +134,7 @@ func (h *TodoHandler) ClickTodo(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Process click action
-	todo, err := h.todoService.Click(r.Context(), id)
+	todo, err := h.todoService.Click(r.Context(), middleware.PrincipalFromRequest(r).UserID, id)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
@@ -141,32 +143,18 @@ func (h *TodoHandler) ClickTodo(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, todo, http.StatusOK)
 }
 
-// Helper function to create an auth middleware
-func createAuthMiddleware(authService auth.AuthService) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from request
-			tokenString, err := authService.ExtractTokenFromRequest(r)
-			if err != nil {
-				respondWithError(w, err, http.StatusUnauthorized)
-				return
-			}
-
-			// Validate token
-			claims, err := authService.ValidateToken(tokenString)
-			if err != nil {
-				respondWithError(w, err, http.StatusUnauthorized)
-				return
-			}
-
-			// Store user ID in request context
-			ctx := r.Context()
-			r = r.WithContext(ctx)
-
-			// Set user ID in header for use in handlers
-			r.Header.Set("X-User-ID", claims.UserID)
-
-			next.ServeHTTP(w, r)
-		})
+// ReturnTodo handles the request to send a todo from its type column back to the main list
+func (h *TodoHandler) ReturnTodo(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Return to main list
+	todo, err := h.todoService.Return(r.Context(), middleware.PrincipalFromRequest(r).UserID, id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
 	}
-}
\ No newline at end of file
+
+	respondWithJSON(w, todo, http.StatusOK)
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/middleware"
+	"github.com/gorilla/mux"
+)
+
+// avatarFormField is the multipart form field holding an avatar upload
+const avatarFormField = "avatar"
+
+// maxAvatarRequestSize leaves room for the multipart headers around the largest avatar
+const maxAvatarRequestSize = service.MaxAvatarSize + 64<<10
+
+// avatarUploadTypes lists the content types accepted for avatar uploads
+var avatarUploadTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// UploadAvatar handles avatar upload request from a multipart/form-data body
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may update this profile
+	if !h.policy.CanUpdate(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Read the image from the form, rejecting oversized requests before parsing them
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarRequestSize)
+	if err := r.ParseMultipartForm(maxAvatarRequestSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithDomainError(w, service.ErrAvatarTooLarge)
+			return
+		}
+		respondWithError(w, errors.New("request must be multipart/form-data with an avatar file"), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile(avatarFormField)
+	if err != nil {
+		respondWithError(w, errors.New("request must be multipart/form-data with an avatar file"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// The declared type is checked here, the actual format by the service
+	contentType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	if !avatarUploadTypes[contentType] {
+		respondWithDomainError(w, service.ErrUnsupportedAvatarType)
+		return
+	}
+	if header.Size > service.MaxAvatarSize {
+		respondWithDomainError(w, service.ErrAvatarTooLarge)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Store the avatar
+	user, err := h.userService.SetAvatar(r.Context(), id, data)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, user, http.StatusOK)
+}
+
+// GetAvatar handles get avatar request, serving the image or with size=thumbnail its square thumbnail
+func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may view this profile
+	if !h.policy.CanView(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	avatar, err := h.userService.GetAvatar(r.Context(), id, r.URL.Query().Get("size") == "thumbnail")
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// ServeContent answers conditional and range requests from the update time
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", avatar.UpdatedAt, bytes.NewReader(avatar.Data))
+}
+
+// DeleteAvatar handles delete avatar request
+func (h *UserHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	id := mux.Vars(r)["id"]
+
+	// Check if user may update this profile
+	if !h.policy.CanUpdate(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	user, err := h.userService.DeleteAvatar(r.Context(), id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, user, http.StatusOK)
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/middleware"
+)
+
+// Bulk file formats
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// maxImportSize is the largest bulk import upload accepted, in bytes
+const maxImportSize = 10 << 20
+
+// exportCSVHeader lists the columns of a CSV export. Password hashes and MFA secrets are never exported.
+var exportCSVHeader = []string{"id", "name", "email", "role", "email_verified", "mfa_enabled", "created_at"}
+
+// ImportUsers handles bulk user import request from a CSV or NDJSON upload
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	// Only admins may import users
+	if !h.policy.CanImport(middleware.PrincipalFromRequest(r)) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dryRun"))
+
+	// Parse the upload in the format given by the query or the content type
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var rows []*model.ImportUserRow
+	var err error
+	switch bulkFormat(query.Get("format"), r.Header.Get("Content-Type")) {
+	case formatCSV:
+		rows, err = decodeCSVUsers(body)
+	case formatNDJSON:
+		rows, err = decodeNDJSONUsers(body)
+	default:
+		respondWithError(w, errors.New("upload must be text/csv or application/x-ndjson"), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Import users, reporting invalid rows in the result
+	result, err := h.userService.ImportUsers(r.Context(), rows, dryRun)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// ExportUsers handles bulk user export request, streaming every user as CSV or NDJSON
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	// Only admins may export users
+	if !h.policy.CanExport(middleware.PrincipalFromRequest(r)) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Pick the format from the query or the accepted content type, NDJSON by default
+	format := bulkFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if format == "" {
+		format = formatNDJSON
+	}
+
+	// Write users as they are read. Errors after the first user cannot change the
+	// status any more, so they end the response early.
+	var write func(*model.User) error
+	var flush func() error
+	switch format {
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := writer.Write(exportCSVHeader); err != nil {
+			return
+		}
+		write = func(user *model.User) error {
+			return writer.Write([]string{
+				user.ID,
+				user.Name,
+				user.Email,
+				string(user.GetRole()),
+				strconv.FormatBool(user.EmailVerified),
+				strconv.FormatBool(user.MFA.Enabled),
+				user.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+		write = func(user *model.User) error {
+			return encoder.Encode(user)
+		}
+		flush = func() error {
+			return nil
+		}
+	}
+
+	err := h.userService.ExportUsers(r.Context(), write)
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		log.Printf("User export failed: %v", err)
+	}
+}
+
+// bulkFormat returns the bulk file format named by the query parameter, or else
+// implied by the media type, or an empty string if neither is supported
+func bulkFormat(format, mediaType string) string {
+	switch strings.ToLower(format) {
+	case formatCSV:
+		return formatCSV
+	case formatNDJSON, "jsonl":
+		return formatNDJSON
+	}
+
+	mediaType, _, _ = mime.ParseMediaType(mediaType)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return formatNDJSON
+	}
+	return ""
+}
+
+// decodeCSVUsers reads import rows from CSV with a header row naming the name, email,
+// and optional password and role columns in any order
+func decodeCSVUsers(r io.Reader) ([]*model.ImportUserRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV upload is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	// Map column names to positions, ignoring a byte order mark added by spreadsheets
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must have a %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	rows := []*model.ImportUserRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		rows = append(rows, &model.ImportUserRow{
+			Row:      len(rows) + 1,
+			Name:     strings.TrimSpace(field(record, "name")),
+			Email:    strings.TrimSpace(field(record, "email")),
+			Password: field(record, "password"), // Spaces may be part of the password
+			Role:     model.Role(strings.TrimSpace(field(record, "role"))),
+		})
+	}
+
+	return rows, nil
+}
+
+// decodeNDJSONUsers reads import rows from one JSON object per line, skipping blank lines
+func decodeNDJSONUsers(r io.Reader) ([]*model.ImportUserRow, error) {
+	scanner := bufio.NewScanner(r)
+
+	rows := []*model.ImportUserRow{}
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &model.ImportUserRow{}
+		if err := json.Unmarshal(data, row); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", line, err)
+		}
+		row.Row = len(rows) + 1
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid NDJSON: %w", err)
+	}
+
+	return rows, nil
+}
@@ -2,12 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/service"
 	"backend-challenge/internal/infrastructure/auth"
+	"backend-challenge/internal/infrastructure/middleware"
+	"backend-challenge/pkg/cursor"
 	"github.com/gorilla/mux"
 )
 
@@ -15,6 +19,7 @@ import (
 type UserHandler struct {
 	userService service.UserService
 	authService auth.AuthService
+	policy      service.UserPolicy
 }
 
 // RegisterUserHandler registers user routes
@@ -22,45 +27,26 @@ func RegisterUserHandler(r *mux.Router, userService service.UserService, authSer
 	handler := &UserHandler{
 		userService: userService,
 		authService: authService,
+		policy:      service.NewUserPolicy(),
 	}
 
 	// Define protected routes
 	protected := r.PathPrefix("/api/users").Subrouter()
-	protected.Use(handler.AuthMiddleware)
+	protected.Use(middleware.AuthMiddleware(authService, middleware.AllowAPIKeys(model.ScopeUsersRead, model.ScopeUsersWrite)))
 
 	// Register routes
 	protected.HandleFunc("", handler.ListUsers).Methods("GET")
+	protected.HandleFunc("/import", handler.ImportUsers).Methods("POST")
+	protected.HandleFunc("/export", handler.ExportUsers).Methods("GET")
 	protected.HandleFunc("/{id}", handler.GetUser).Methods("GET")
 	protected.HandleFunc("/{id}", handler.UpdateUser).Methods("PUT")
 	protected.HandleFunc("/{id}", handler.DeleteUser).Methods("DELETE")
-}
-
-// AuthMiddleware verifies JWT token
-func (h *UserHandler) AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract token from request
-		tokenString, err := h.authService.ExtractTokenFromRequest(r)
-		if err != nil {
-			respondWithError(w, err, http.StatusUnauthorized)
-			return
-		}
-
-		// Validate token
-		claims, err := h.authService.ValidateToken(tokenString)
-		if err != nil {
-			respondWithError(w, err, http.StatusUnauthorized)
-			return
-		}
-
-		// Store user ID in request context
-		ctx := r.Context()
-		r = r.WithContext(ctx)
-
-		// Set user ID in header for use in handlers
-		r.Header.Set("X-User-ID", claims.UserID)
-
-		next.ServeHTTP(w, r)
-	})
+	protected.HandleFunc("/{id}/restore", handler.RestoreUser).Methods("POST")
+	protected.HandleFunc("/{id}/role", handler.UpdateUserRole).Methods("PUT")
+	protected.HandleFunc("/{id}/password", handler.ChangePassword).Methods("PUT")
+	protected.HandleFunc("/{id}/avatar", handler.UploadAvatar).Methods("PUT")
+	protected.HandleFunc("/{id}/avatar", handler.GetAvatar).Methods("GET")
+	protected.HandleFunc("/{id}/avatar", handler.DeleteAvatar).Methods("DELETE")
 }
 
 // GetUser handles get user by ID request
@@ -69,6 +55,12 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	// Check if user may view this profile
+	if !h.policy.CanView(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
 	// Get user from service
 	user, err := h.userService.GetByID(r.Context(), id)
 	if err != nil {
@@ -81,47 +73,90 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 // ListUsers handles list users request
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters for pagination
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("pageSize")
+	// Only admins may list every user
+	if !h.policy.CanList(middleware.PrincipalFromRequest(r)) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	query := r.URL.Query()
 
-	// Default values
-	page, pageSize := 1, 10
+	// Parse search and sort parameters
+	opts := &model.UserListOptions{
+		Search:   query.Get("search"),
+		SortBy:   model.UserSortField(query.Get("sort")),
+		Order:    model.SortOrder(query.Get("order")),
+		Page:     1,
+		PageSize: 10,
+	}
+	switch query.Get("match") {
+	case "", "prefix":
+		opts.Prefix = true
+	case "contains":
+	default:
+		respondWithError(w, errors.New("match must be prefix or contains"), http.StatusBadRequest)
+		return
+	}
+
+	// Parse creation time range
+	var err error
+	if from := query.Get("createdFrom"); from != "" {
+		if opts.CreatedFrom, err = time.Parse(time.RFC3339, from); err != nil {
+			respondWithError(w, errors.New("createdFrom must be an RFC 3339 time"), http.StatusBadRequest)
+			return
+		}
+	}
+	if to := query.Get("createdTo"); to != "" {
+		if opts.CreatedTo, err = time.Parse(time.RFC3339, to); err != nil {
+			respondWithError(w, errors.New("createdTo must be an RFC 3339 time"), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Parse page
-	if pageStr != "" {
+	if pageStr := query.Get("page"); pageStr != "" {
 		if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
-			page = pageVal
+			opts.Page = pageVal
 		}
 	}
 
-	// Parse page size
+	// Parse page size, also accepted as limit for cursor pagination
+	pageSizeStr := query.Get("pageSize")
+	if limit := query.Get("limit"); limit != "" {
+		pageSizeStr = limit
+	}
 	if pageSizeStr != "" {
 		if pageSizeVal, err := strconv.Atoi(pageSizeStr); err == nil && pageSizeVal > 0 && pageSizeVal <= 100 {
-			pageSize = pageSizeVal
+			opts.PageSize = pageSizeVal
 		}
 	}
 
-	// Get users
-	users, err := h.userService.ListUsers(r.Context(), page, pageSize)
-	if err != nil {
-		respondWithDomainError(w, err)
-		return
+	// Parse cursor, which takes precedence over the page
+	if after := query.Get("after"); after != "" {
+		c, err := cursor.Decode(after)
+		if err != nil {
+			respondWithDomainError(w, err)
+			return
+		}
+		opts.After = &c
 	}
 
-	// Get total count
-	totalItems, err := h.userService.CountUsers(r.Context())
+	// Get users with the total number of matches
+	users, totalItems, err := h.userService.ListUsers(r.Context(), opts)
 	if err != nil {
 		respondWithDomainError(w, err)
 		return
 	}
 
-	// Create response
+	// Create response, with the total only for offset pages
 	response := ResponseWithPagination{
 		Data:       users,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalItems: totalItems,
+		Page:       opts.Page,
+		PageSize:   opts.PageSize,
+		NextCursor: opts.NextCursor(users),
+	}
+	if opts.After == nil {
+		response.TotalItems = &totalItems
 	}
 
 	respondWithJSON(w, response, http.StatusOK)
@@ -133,10 +168,10 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Check if user is updating their own profile
-	userID := r.Header.Get("X-User-ID")
-	if userID != id {
-		respondWithError(w, service.ErrUserNotFound, http.StatusNotFound)
+	// Check if user may update this profile
+	principal := middleware.PrincipalFromRequest(r)
+	if !h.policy.CanUpdate(principal, id) {
+		respondWithDomainError(w, service.ErrForbidden)
 		return
 	}
 
@@ -147,6 +182,12 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// API keys cannot change emails, so that a leaked key cannot take over the account
+	if input.Email != "" && principal.IsAPIKey() {
+		respondWithDomainError(w, service.ErrInsufficientScope)
+		return
+	}
+
 	// Update user
 	user, err := h.userService.UpdateUser(r.Context(), id, &input)
 	if err != nil {
@@ -163,10 +204,9 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Check if user is deleting their own profile
-	userID := r.Header.Get("X-User-ID")
-	if userID != id {
-		respondWithError(w, service.ErrUserNotFound, http.StatusNotFound)
+	// Check if user may delete this profile
+	if !h.policy.CanDelete(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
 		return
 	}
 
@@ -182,5 +222,110 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		Message: "User deleted successfully",
 	}
 
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// RestoreUser handles restore deleted user request
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Only admins may restore users
+	if !h.policy.CanRestore(middleware.PrincipalFromRequest(r), id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// Restore user
+	user, err := h.userService.RestoreUser(r.Context(), id)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, user, http.StatusOK)
+}
+
+// UpdateUserRole handles change user role request
+func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Only admins may change roles
+	principal := middleware.PrincipalFromRequest(r)
+	if !h.policy.CanChangeRole(principal, id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// API keys cannot change roles, so that a leaked admin key cannot hand out admin rights
+	if principal.IsAPIKey() {
+		respondWithDomainError(w, service.ErrInsufficientScope)
+		return
+	}
+
+	// Parse request body
+	var input model.UpdateRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Change role
+	user, err := h.userService.ChangeRole(r.Context(), id, input.Role)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, user, http.StatusOK)
+}
+
+// ChangePassword handles change password request and signs the user out of their other sessions
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Only users may change their own password
+	principal := middleware.PrincipalFromRequest(r)
+	if !h.policy.CanChangePassword(principal, id) {
+		respondWithDomainError(w, service.ErrForbidden)
+		return
+	}
+
+	// API keys cannot change passwords, so that a leaked key cannot take over the account
+	if principal.IsAPIKey() {
+		respondWithDomainError(w, service.ErrInsufficientScope)
+		return
+	}
+
+	// Parse request body
+	var input model.ChangePasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Change password
+	user, err := h.userService.ChangePassword(r.Context(), id, &input)
+	if err != nil {
+		respondWithLoginError(w, err)
+		return
+	}
+
+	// Tokens issued to other devices with the old password must not stay valid
+	if err := h.authService.RevokeOtherSessions(r.Context(), principal.UserID, principal.SessionID); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SuccessResponse{
+		Message: "Password changed successfully",
+		Data:    user,
+	}
+
 	respondWithJSON(w, response, http.StatusOK)
 }
\ No newline at end of file
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// API key scopes
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{ScopeTodosRead, ScopeTodosWrite, ScopeUsersRead, ScopeUsersWrite}
+
+// APIKey represents a personal API key for machine clients. Only the hash of the
+// key is persisted, the prefix is kept so that users can recognise their keys.
+type APIKey struct {
+	ID         string    `json:"id" bson:"_id,omitempty"`
+	UserID     string    `json:"user_id" bson:"user_id"`
+	Name       string    `json:"name" bson:"name"`
+	Prefix     string    `json:"prefix" bson:"prefix"`
+	KeyHash    string    `json:"-" bson:"key_hash"`
+	Scopes     []string  `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyInput represents the input for creating an API key
+type CreateAPIKeyInput struct {
+	Name      string    `json:"name" validate:"required,max=100"`
+	Scopes    []string  `json:"scopes" validate:"required"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"` // Optional, the key never expires if unset
+}
+
+// CreatedAPIKey is returned once when an API key is created. The key is never shown again.
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// IsValidScope reports whether the scope is a known API key scope
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key can be used at the given time
+func (k *APIKey) IsActive(now time.Time) bool {
+	if !k.RevokedAt.IsZero() {
+		return false
+	}
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,101 @@
+package model
+
+import "time"
+
+// AuditAction identifies the kind of a security-relevant event
+type AuditAction string
+
+// Audit actions
+const (
+	AuditLoginSucceeded   AuditAction = "login.succeeded"
+	AuditLoginFailed      AuditAction = "login.failed"
+	AuditUserRegistered   AuditAction = "user.registered"
+	AuditUserImported     AuditAction = "user.imported"
+	AuditUserUpdated      AuditAction = "user.updated"
+	AuditUserDeleted      AuditAction = "user.deleted"
+	AuditUserRestored     AuditAction = "user.restored"
+	AuditRoleChanged      AuditAction = "user.role_changed"
+	AuditPasswordChanged  AuditAction = "user.password_changed"
+	AuditPasswordReset    AuditAction = "user.password_reset"
+	AuditMFADisabled      AuditAction = "user.mfa_disabled"
+	AuditTokenRevoked     AuditAction = "token.revoked"
+	AuditAllTokensRevoked AuditAction = "token.revoked_all"
+	AuditSessionRevoked   AuditAction = "session.revoked"
+	AuditAPIKeyCreated    AuditAction = "api_key.created"
+	AuditAPIKeyRevoked    AuditAction = "api_key.revoked"
+	AuditDataExported     AuditAction = "user.data_exported"
+	AuditErasureRequested AuditAction = "user.erasure_requested"
+	AuditUserErased       AuditAction = "user.erased"
+)
+
+// AuditPersonalDetails lists the event details that identify a user, removed when the user is erased
+var AuditPersonalDetails = []string{"email", "previous_email"}
+
+// AuditEvent records who did what to whom, from where and when. Events are only
+// changed when a user is erased, which anonymizes the events they took part in.
+type AuditEvent struct {
+	ID        string            `json:"id" bson:"_id,omitempty"`
+	Action    AuditAction       `json:"action" bson:"action"`
+	ActorID   string            `json:"actor_id,omitempty" bson:"actor_id,omitempty"`   // Authenticated user performing the action
+	TargetID  string            `json:"target_id,omitempty" bson:"target_id,omitempty"` // User the action applies to
+	IP        string            `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
+}
+
+// AuditFilter selects audit events. Empty fields match every event.
+type AuditFilter struct {
+	Action   AuditAction
+	ActorID  string
+	TargetID string
+	UserID   string    // Matches events where the user is the actor or the target
+	From     time.Time // Inclusive
+	To       time.Time // Exclusive
+	Page     int
+	PageSize int
+}
+
+// Matches reports whether the event passes the filter, ignoring pagination
+func (f *AuditFilter) Matches(event *AuditEvent) bool {
+	if f.Action != "" && event.Action != f.Action {
+		return false
+	}
+	if f.ActorID != "" && event.ActorID != f.ActorID {
+		return false
+	}
+	if f.TargetID != "" && event.TargetID != f.TargetID {
+		return false
+	}
+	if f.UserID != "" && event.ActorID != f.UserID && event.TargetID != f.UserID {
+		return false
+	}
+	if !f.From.IsZero() && event.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !event.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Anonymize replaces the user's ID with a pseudonym and removes the details that
+// identify them. It reports whether the user took part in the event.
+func (e *AuditEvent) Anonymize(userID, pseudonym string) bool {
+	if e.ActorID != userID && e.TargetID != userID {
+		return false
+	}
+
+	if e.ActorID == userID {
+		e.ActorID = pseudonym
+	}
+	if e.TargetID == userID {
+		e.TargetID = pseudonym
+	}
+	e.IP = ""
+	e.UserAgent = ""
+	for _, key := range AuditPersonalDetails {
+		delete(e.Details, key)
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+package model
+
+import "context"
+
+// ClientInfo describes the client that sent a request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// clientInfoKey is the context key for ClientInfo
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of the context carrying the client info
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client info of the request, or an empty value if unknown
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginAttempts tracks recent failed logins for a throttling key, such as an email or client IP
+type LoginAttempts struct {
+	Key           string    `json:"key" bson:"_id"`
+	Failures      int       `json:"failures" bson:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at" bson:"last_failure_at"`
+	ExpiresAt     time.Time `json:"expires_at" bson:"expires_at"`
+}
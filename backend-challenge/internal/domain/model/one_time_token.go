@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// TokenPurpose identifies what a one-time token can be used for
+type TokenPurpose string
+
+const (
+	// TokenPurposePasswordReset allows setting a new password without knowing the old one
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+	// TokenPurposeEmailVerification confirms that the user owns their email address
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+// OneTimeToken represents a single-use token sent to a user by email.
+// Only the hash of the token is persisted.
+type OneTimeToken struct {
+	ID        string       `json:"id" bson:"_id,omitempty"`
+	UserID    string       `json:"user_id" bson:"user_id"`
+	Purpose   TokenPurpose `json:"purpose" bson:"purpose"`
+	TokenHash string       `json:"-" bson:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time    `json:"created_at" bson:"created_at"`
+	UsedAt    time.Time    `json:"used_at,omitempty" bson:"used_at,omitempty"`
+}
+
+// ForgotPasswordInput represents the input for requesting a password reset
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput represents the input for setting a new password with a reset token
+type ResetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// IsExpired reports whether the token has expired at the given time
+func (t *OneTimeToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsUsed reports whether the token has already been used
+func (t *OneTimeToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
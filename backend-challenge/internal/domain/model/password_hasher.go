@@ -0,0 +1,209 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes passwords. Hashes are stored in a self-describing format
+// ("$2a$..." for bcrypt, "$argon2id$..." for argon2id) that records the algorithm
+// and its settings, so that hashes of every supported algorithm can be verified.
+type PasswordHasher interface {
+	// Hash hashes a password with the current settings
+	Hash(password string) (string, error)
+
+	// Verify checks a password against a hash of any supported algorithm
+	Verify(password, hash string) bool
+
+	// NeedsRehash reports whether a hash was created with another algorithm or other settings
+	NeedsRehash(hash string) bool
+}
+
+// Password hashing algorithms
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+// Argon2idParams configures argon2id hashing. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams returns the argon2id settings recommended by OWASP
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// DefaultPasswordHasher returns the hasher used when none is configured
+func DefaultPasswordHasher() PasswordHasher {
+	return NewBcryptHasher(bcrypt.DefaultCost)
+}
+
+// VerifyPassword checks a password against a hash of any supported algorithm
+func VerifyPassword(password, hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// bcryptHasher implements PasswordHasher with bcrypt
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a bcrypt PasswordHasher with the given cost.
+// Costs outside the range supported by bcrypt are clamped.
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.DefaultCost
+	}
+	if cost > bcrypt.MaxCost {
+		cost = bcrypt.MaxCost
+	}
+
+	return &bcryptHasher{cost: cost}
+}
+
+// Hash hashes a password with bcrypt
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify checks a password against a hash of any supported algorithm
+func (h *bcryptHasher) Verify(password, hash string) bool {
+	return VerifyPassword(password, hash)
+}
+
+// NeedsRehash reports whether a hash is not a bcrypt hash with the configured cost
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+// argon2idHasher implements PasswordHasher with argon2id
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher creates an argon2id PasswordHasher with the given settings
+func NewArgon2idHasher(params Argon2idParams) PasswordHasher {
+	defaults := DefaultArgon2idParams()
+	if params.Memory == 0 {
+		params.Memory = defaults.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = defaults.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaults.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+
+	return &argon2idHasher{params: params}
+}
+
+// Hash hashes a password with argon2id and encodes it in PHC string format
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks a password against a hash of any supported algorithm
+func (h *argon2idHasher) Verify(password, hash string) bool {
+	return VerifyPassword(password, hash)
+}
+
+// NeedsRehash reports whether a hash is not an argon2id hash with the configured settings
+func (h *argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) != h.params.SaltLength ||
+		uint32(len(key)) != h.params.KeyLength
+}
+
+// decodeArgon2id parses an argon2id hash in PHC string format
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
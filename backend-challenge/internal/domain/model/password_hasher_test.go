@@ -0,0 +1,59 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(4)
+	argonHasher := NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+
+	bcryptHash, err := bcryptHasher.Hash("password123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	argonHash, err := argonHasher.Hash("password123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The hash records its algorithm
+	if !strings.HasPrefix(bcryptHash, "$2a$04$") {
+		t.Errorf("Expected a bcrypt hash with cost 4, got %s", bcryptHash)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Expected an argon2id hash, got %s", argonHash)
+	}
+
+	// Every hasher verifies hashes of every algorithm
+	for _, hasher := range []PasswordHasher{bcryptHasher, argonHasher} {
+		for _, hash := range []string{bcryptHash, argonHash} {
+			if !hasher.Verify("password123", hash) {
+				t.Errorf("Expected password to match %s", hash)
+			}
+			if hasher.Verify("wrongpassword", hash) {
+				t.Errorf("Expected wrong password not to match %s", hash)
+			}
+		}
+	}
+
+	// Hashes from another algorithm or other settings need a rehash
+	if bcryptHasher.NeedsRehash(bcryptHash) || argonHasher.NeedsRehash(argonHash) {
+		t.Errorf("Expected hashes with the current settings not to need a rehash")
+	}
+	if !bcryptHasher.NeedsRehash(argonHash) || !argonHasher.NeedsRehash(bcryptHash) {
+		t.Errorf("Expected hashes from another algorithm to need a rehash")
+	}
+	if !NewBcryptHasher(5).NeedsRehash(bcryptHash) {
+		t.Errorf("Expected a bcrypt hash with a lower cost to need a rehash")
+	}
+	if !NewArgon2idHasher(DefaultArgon2idParams()).NeedsRehash(argonHash) {
+		t.Errorf("Expected an argon2id hash with other parameters to need a rehash")
+	}
+
+	// Malformed hashes never match
+	if VerifyPassword("password123", "$argon2id$v=19$m=1024$broken") {
+		t.Errorf("Expected malformed hash not to match")
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import "context"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID    string
+	Email     string
+	Role      Role
+	Scopes    []string // Scopes of the API key; empty for tokens from a user login
+	TokenID   string   // ID of the access token, empty for API keys
+	SessionID string   // Login session of the access token, empty for API keys and tokens without one
+	APIKeyID  string   // Set when the caller authenticated with an API key
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// IsAPIKey reports whether the principal authenticated with an API key
+func (p Principal) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+// HasScope reports whether the principal may use a scope. Tokens from a user
+// login grant every scope, API keys only the scopes they were created with.
+func (p Principal) HasScope(scope string) bool {
+	if !p.IsAPIKey() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the context key for Principal
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the authenticated caller
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller of the request, if any
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// PrivacyJobType identifies what a privacy job does with a user's personal data
+type PrivacyJobType string
+
+const (
+	// PrivacyJobExport collects everything held about a user into an archive
+	PrivacyJobExport PrivacyJobType = "export"
+	// PrivacyJobErasure deletes a user and their data, and anonymizes their audit trail
+	PrivacyJobErasure PrivacyJobType = "erasure"
+)
+
+// PrivacyJobStatus is the progress of a privacy job
+type PrivacyJobStatus string
+
+const (
+	PrivacyJobPending   PrivacyJobStatus = "pending"
+	PrivacyJobRunning   PrivacyJobStatus = "running"
+	PrivacyJobCompleted PrivacyJobStatus = "completed"
+	PrivacyJobFailed    PrivacyJobStatus = "failed"
+)
+
+// PrivacyJob is a data export or erasure request, run in the background.
+// Erasure jobs keep the ID of the erased user as a record that the request was carried out.
+type PrivacyJob struct {
+	ID          string           `json:"id" bson:"_id,omitempty"`
+	Type        PrivacyJobType   `json:"type" bson:"type"`
+	UserID      string           `json:"user_id" bson:"user_id"`
+	RequestedBy string           `json:"requested_by" bson:"requested_by"`
+	Status      PrivacyJobStatus `json:"status" bson:"status"`
+	Error       string           `json:"error,omitempty" bson:"error,omitempty"`
+	Archive     *UserDataArchive `json:"-" bson:"archive,omitempty"` // Set once an export completes
+	CreatedAt   time.Time        `json:"created_at" bson:"created_at"`
+	StartedAt   time.Time        `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt time.Time        `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt   time.Time        `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // Exports are removed after this time
+}
+
+// IsDone reports whether the job has finished, successfully or not
+func (j *PrivacyJob) IsDone() bool {
+	return j.Status == PrivacyJobCompleted || j.Status == PrivacyJobFailed
+}
+
+// UserDataArchive holds everything stored about a user, as returned by a data export.
+// Secrets such as password hashes and key hashes are left out.
+type UserDataArchive struct {
+	GeneratedAt time.Time     `json:"generated_at" bson:"generated_at"`
+	User        *User         `json:"user" bson:"user"`
+	Todos       []*TodoItem   `json:"todos" bson:"todos"`
+	Sessions    []*Session    `json:"sessions" bson:"sessions"`
+	APIKeys     []*APIKey     `json:"api_keys" bson:"api_keys"`
+	AuditEvents []*AuditEvent `json:"audit_events" bson:"audit_events"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// RefreshToken represents a stored refresh token. Only the hash of the token is persisted.
+// Tokens issued from the same login share a FamilyID so that the whole chain can be revoked
+// when a rotated token is presented again.
+type RefreshToken struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	FamilyID  string    `json:"family_id" bson:"family_id"`
+	TokenHash string    `json:"-" bson:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UsedAt    time.Time `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	RevokedAt time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// RefreshTokenInput represents the input for exchanging a refresh token
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// IsExpired reports whether the refresh token has expired at the given time
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsUsed reports whether the refresh token has already been rotated
+func (t *RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+// IsRevoked reports whether the refresh token has been revoked
+func (t *RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
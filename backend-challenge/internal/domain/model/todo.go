@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,28 +25,75 @@ const (
 	StatusColumn ItemStatus = "COLUMN"
 )
 
+// IsValid reports whether the item type is a known type
+func (t ItemType) IsValid() bool {
+	return t == TypeFruit || t == TypeVegetable
+}
+
+// DefaultReturnAfter is how long a clicked item stays in its type column when no
+// other return delay is configured
+const DefaultReturnAfter = 5 * time.Second
+
+// Duration is a time.Duration written in JSON as a Go duration string such as "30s" or "2m"
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
 // TodoItem represents a todo item in the system
 type TodoItem struct {
 	ID        string     `json:"id" bson:"_id,omitempty"`
+	OwnerID   string     `json:"owner_id" bson:"owner_id"` // Only the owner can see and change the item
 	Type      ItemType   `json:"type" bson:"type"`
 	Name      string     `json:"name" bson:"name"`
 	Status    ItemStatus `json:"status" bson:"status"`
+
+	// ReturnAfter overrides how long the item stays in its type column once clicked
+	ReturnAfter Duration `json:"return_after,omitempty" bson:"return_after,omitempty"`
+
 	ClickedAt time.Time  `json:"clicked_at,omitempty" bson:"clicked_at,omitempty"`
 	ReturnAt  time.Time  `json:"return_at,omitempty" bson:"return_at,omitempty"`
+
+	// ReturnedAt is when the item last came back to the main list
+	ReturnedAt time.Time `json:"returned_at,omitempty" bson:"returned_at,omitempty"`
+
 	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+
+	// Version is incremented on every write, so that updates of an item that
+	// changed since it was read can be detected
+	Version int64 `json:"-" bson:"version"`
 }
 
 // CreateTodoInput represents the input for creating a todo item
 type CreateTodoInput struct {
-	Type ItemType `json:"type" validate:"required"`
-	Name string   `json:"name" validate:"required,min=1,max=100"`
+	Type        ItemType  `json:"type" validate:"required"`
+	Name        string    `json:"name" validate:"required,min=1,max=100"`
+	ReturnAfter *Duration `json:"return_after,omitempty"`
 }
 
-// UpdateTodoInput represents the input for updating a todo item
+// UpdateTodoInput represents the input for updating a todo item. A ReturnAfter
+// of zero removes the override of the item.
 type UpdateTodoInput struct {
-	Type ItemType `json:"type" validate:"omitempty"`
-	Name string   `json:"name" validate:"omitempty,min=1,max=100"`
+	Type        ItemType  `json:"type" validate:"omitempty"`
+	Name        string    `json:"name" validate:"omitempty,min=1,max=100"`
+	ReturnAfter *Duration `json:"return_after,omitempty"`
 }
 
 // TodosGrouped represents todos grouped by status and type
@@ -54,17 +102,22 @@ type TodosGrouped struct {
 	Column map[ItemType][]*TodoItem `json:"column"`
 }
 
-// NewTodoItem creates a new todo item
-func NewTodoItem(input *CreateTodoInput) *TodoItem {
+// NewTodoItem creates a new todo item owned by a user
+func NewTodoItem(ownerID string, input *CreateTodoInput) *TodoItem {
 	now := time.Now()
-	return &TodoItem{
+	todo := &TodoItem{
 		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
 		Type:      input.Type,
 		Name:      input.Name,
 		Status:    StatusMain,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
+	if input.ReturnAfter != nil {
+		todo.ReturnAfter = *input.ReturnAfter
+	}
+	return todo
 }
 
 // Update updates a todo item with the provided input
@@ -75,20 +128,33 @@ func (t *TodoItem) Update(input *UpdateTodoInput) {
 	if input.Name != "" {
 		t.Name = input.Name
 	}
+	if input.ReturnAfter != nil {
+		t.ReturnAfter = *input.ReturnAfter
+	}
 	t.UpdatedAt = time.Now()
 }
 
-// Click marks a todo item as clicked and sets it to return after a specific duration
-func (t *TodoItem) Click() {
+// Click marks a todo item as clicked and sets it to return after the given delay
+func (t *TodoItem) Click(returnAfter time.Duration) {
 	now := time.Now()
 	t.ClickedAt = now
-	t.ReturnAt = now.Add(5 * time.Second)
+	t.ReturnAt = now.Add(returnAfter)
 	t.Status = StatusColumn
 	t.UpdatedAt = now
 }
 
 // Return marks a todo item as returned to the main list
 func (t *TodoItem) Return() {
+	now := time.Now()
 	t.Status = StatusMain
-	t.UpdatedAt = time.Now()
+	t.ReturnedAt = now
+	t.UpdatedAt = now
+}
+
+// InMainSince returns when the item entered the main list, on creation or its last return
+func (t *TodoItem) InMainSince() time.Time {
+	if t.ReturnedAt.After(t.CreatedAt) {
+		return t.ReturnedAt
+	}
+	return t.CreatedAt
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCreateTodoInputReturnAfter(t *testing.T) {
+	// Test case: return_after is a duration string
+	var input CreateTodoInput
+	if err := json.Unmarshal([]byte(`{"type":"Fruit","name":"Apple","return_after":"1m30s"}`), &input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if input.ReturnAfter == nil || time.Duration(*input.ReturnAfter) != 90*time.Second {
+		t.Fatalf("Expected return after 1m30s, got %v", input.ReturnAfter)
+	}
+
+	// Test case: the item keeps the override and writes it back as a string
+	todo := NewTodoItem("user-1", &input)
+	data, err := json.Marshal(todo)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded["return_after"] != "1m30s" {
+		t.Errorf("Expected return_after 1m30s, got %v", decoded["return_after"])
+	}
+
+	// Test case: invalid durations are rejected
+	if err := json.Unmarshal([]byte(`{"return_after":"soon"}`), &input); err == nil {
+		t.Errorf("Expected error for invalid duration")
+	}
+}
+
+func TestTodoClick(t *testing.T) {
+	todo := NewTodoItem("user-1", &CreateTodoInput{Type: TypeFruit, Name: "Apple"})
+	todo.Click(time.Minute)
+
+	if todo.Status != StatusColumn {
+		t.Errorf("Expected status %s, got %s", StatusColumn, todo.Status)
+	}
+	if got := todo.ReturnAt.Sub(todo.ClickedAt); got != time.Minute {
+		t.Errorf("Expected return after %v, got %v", time.Minute, got)
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RevokedToken represents an access token that was revoked before it expired.
+// Entries only need to be kept until the token would have expired anyway.
+type RevokedToken struct {
+	ID        string    `json:"id" bson:"_id"` // The token's jti claim
+	UserID    string    `json:"user_id" bson:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at" bson:"revoked_at"`
+}
+
+// UserTokenRevocation revokes every token of a user issued at or before RevokedBefore
+type UserTokenRevocation struct {
+	UserID        string    `json:"user_id" bson:"_id"`
+	RevokedBefore time.Time `json:"revoked_before" bson:"revoked_before"`
+}
@@ -1,31 +1,134 @@
 package model
 
-import (
-	"time"
+import "time"
 
-	"golang.org/x/crypto/bcrypt"
+// Role represents the role of a user
+type Role string
+
+const (
+	// RoleUser is the default role with access to the user's own account only
+	RoleUser Role = "user"
+	// RoleAdmin can manage every user account
+	RoleAdmin Role = "admin"
 )
 
+// IsValid reports whether the role is a known role
+func (r Role) IsValid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
 // User represents a user in the system
 type User struct {
-	ID        string    `json:"id" bson:"_id,omitempty"`
-	Name      string    `json:"name" bson:"name"`
-	Email     string    `json:"email" bson:"email"`
-	Password  string    `json:"-" bson:"password"` // Never return password in JSON responses
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ID            string     `json:"id" bson:"_id,omitempty"`
+	Name          string     `json:"name" bson:"name"`
+	Email         string     `json:"email" bson:"email"`
+	Password      string     `json:"-" bson:"password"` // Never return password in JSON responses
+	Role          Role       `json:"role" bson:"role,omitempty"`
+	EmailVerified bool       `json:"email_verified" bson:"email_verified"` // Set once the emailed verification link is opened
+	MFA           MFA        `json:"mfa" bson:"mfa"`
+	Profile       Profile    `json:"profile" bson:"profile"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set while soft deleted, until restored or purged
+}
+
+// MFA holds the TOTP two-factor authentication state of a user
+type MFA struct {
+	Enabled       bool     `json:"enabled" bson:"enabled"`
+	Secret        string   `json:"-" bson:"secret,omitempty"`         // Set on enrollment, active once confirmed
+	RecoveryCodes []string `json:"-" bson:"recovery_codes,omitempty"` // Hashes of unused recovery codes
+	LastStep      int64    `json:"-" bson:"last_step,omitempty"`      // Time step of the last accepted code, to prevent replay
+}
+
+// Equal reports whether two MFA states are the same
+func (m MFA) Equal(other MFA) bool {
+	if m.Enabled != other.Enabled || m.Secret != other.Secret || m.LastStep != other.LastStep {
+		return false
+	}
+	if len(m.RecoveryCodes) != len(other.RecoveryCodes) {
+		return false
+	}
+	for i := range m.RecoveryCodes {
+		if m.RecoveryCodes[i] != other.RecoveryCodes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Profile holds the optional details users share about themselves
+type Profile struct {
+	DisplayName string  `json:"display_name,omitempty" bson:"display_name,omitempty"`
+	Locale      string  `json:"locale,omitempty" bson:"locale,omitempty"`     // BCP 47 language tag, e.g. th-TH
+	Timezone    string  `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA time zone, e.g. Asia/Bangkok
+	Bio         string  `json:"bio,omitempty" bson:"bio,omitempty"`
+	Avatar      *Avatar `json:"avatar,omitempty" bson:"avatar,omitempty"`
+}
+
+// Avatar describes a user's profile picture, kept in the blob store with a square thumbnail
+type Avatar struct {
+	ContentType  string    `json:"content_type" bson:"content_type"`
+	Width        int       `json:"width" bson:"width"`
+	Height       int       `json:"height" bson:"height"`
+	Key          string    `json:"-" bson:"key"`
+	ThumbnailKey string    `json:"-" bson:"thumbnail_key"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// MFAEnrollment is returned when a user starts enrolling in two-factor authentication
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACodeInput represents the input for confirming two-factor enrollment with a code
+type MFACodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFADisableInput represents the input for turning off two-factor authentication
+type MFADisableInput struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"` // TOTP or recovery code
+}
+
+// MFALoginInput represents the second login step for users with two-factor authentication
+type MFALoginInput struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required"` // TOTP or recovery code
 }
 
 // RegisterUserInput represents the input for user registration
 type RegisterUserInput struct {
 	Name     string `json:"name" validate:"required,min=2,max=100"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"` // Checked against the password policy
 }
 
-// UpdateUserInput represents the input for updating a user
+// UpdateUserInput represents the input for updating a user.
+// Profile fields are left unchanged when omitted and cleared when empty.
 type UpdateUserInput struct {
-	Name  string `json:"name" validate:"omitempty,min=2,max=100"`
-	Email string `json:"email" validate:"omitempty,email"`
+	Name        string  `json:"name" validate:"omitempty,min=2,max=100"`
+	Email       string  `json:"email" validate:"omitempty,email"`
+	DisplayName *string `json:"displayName,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+	Bio         *string `json:"bio,omitempty"`
+}
+
+// UpdateRoleInput represents the input for changing a user's role
+type UpdateRoleInput struct {
+	Role Role `json:"role" validate:"required"`
+}
+
+// ChangePasswordInput represents the input for changing a user's password
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"` // Checked against the password policy
+}
+
+// ResendVerificationInput represents the input for requesting a new verification email
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
 // LoginUserInput represents the input for user login
@@ -34,9 +137,9 @@ type LoginUserInput struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// NewUser creates a new user from registration input
-func NewUser(input *RegisterUserInput) (*User, error) {
-	hashedPassword, err := HashPassword(input.Password)
+// NewUser creates a new user from registration input, hashing the password with the given hasher
+func NewUser(input *RegisterUserInput, hasher PasswordHasher) (*User, error) {
+	hashedPassword, err := hasher.Hash(input.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -45,23 +148,37 @@ func NewUser(input *RegisterUserInput) (*User, error) {
 		Name:      input.Name,
 		Email:     input.Email,
 		Password:  hashedPassword,
+		Role:      RoleUser,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+// GetRole returns the user's role. Users stored before roles existed are regular users.
+func (u *User) GetRole() Role {
+	if u.Role == "" {
+		return RoleUser
 	}
-	return string(hashed), nil
+	return u.Role
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.GetRole() == RoleAdmin
+}
+
+// IsDeleted reports whether the user has been soft deleted
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+// HashPassword hashes a password with the default hasher
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordHasher().Hash(password)
 }
 
 // CheckPassword verifies the provided password against the user's hashed password
 func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+	return VerifyPassword(password, u.Password)
 }
 
 // Update applies the provided updates to the user
@@ -69,7 +186,21 @@ func (u *User) Update(input *UpdateUserInput) {
 	if input.Name != "" {
 		u.Name = input.Name
 	}
-	if input.Email != "" {
+	if input.Email != "" && input.Email != u.Email {
 		u.Email = input.Email
+		// The new address has not been confirmed yet
+		u.EmailVerified = false
+	}
+	if input.DisplayName != nil {
+		u.Profile.DisplayName = *input.DisplayName
+	}
+	if input.Locale != nil {
+		u.Profile.Locale = *input.Locale
+	}
+	if input.Timezone != nil {
+		u.Profile.Timezone = *input.Timezone
+	}
+	if input.Bio != nil {
+		u.Profile.Bio = *input.Bio
 	}
 }
\ No newline at end of file
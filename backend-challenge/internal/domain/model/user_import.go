@@ -0,0 +1,26 @@
+package model
+
+// ImportUserRow is a user to create in a bulk import
+type ImportUserRow struct {
+	Row      int    `json:"-"` // Position in the uploaded file, starting at 1
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password"` // Optional, users without one set it with a password reset
+	Role     Role   `json:"role"`     // Defaults to the user role
+}
+
+// ImportRowError lists the problems with one row of a bulk import, keyed by field
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Email  string            `json:"email,omitempty"`
+	Errors map[string]string `json:"errors"`
+}
+
+// ImportResult summarizes a bulk import. Valid rows are created even if other rows fail.
+type ImportResult struct {
+	DryRun  bool             `json:"dryRun"`  // Set when rows were only validated
+	Total   int              `json:"total"`   // Rows in the upload
+	Created int              `json:"created"` // Users created, or that would be created in a dry run
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"backend-challenge/pkg/cursor"
+)
+
+// UserSortField is a field users can be sorted by
+type UserSortField string
+
+const (
+	UserSortCreatedAt UserSortField = "created_at"
+	UserSortName      UserSortField = "name"
+	UserSortEmail     UserSortField = "email"
+)
+
+// IsValid reports whether the field is a known sort field
+func (f UserSortField) IsValid() bool {
+	return f == UserSortCreatedAt || f == UserSortName || f == UserSortEmail
+}
+
+// SortOrder is the direction of a sort
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// IsValid reports whether the order is a known sort order
+func (o SortOrder) IsValid() bool {
+	return o == SortAsc || o == SortDesc
+}
+
+// UserListOptions selects, sorts and paginates users. Empty filters match every user.
+// Users are paginated by offset with Page, or by keyset with After when sorted by creation time.
+type UserListOptions struct {
+	Search      string        // Case-insensitive match on name or email
+	Prefix      bool          // Match Search at the start of name or email instead of anywhere
+	CreatedFrom time.Time     // Inclusive
+	CreatedTo   time.Time     // Exclusive
+	SortBy      UserSortField // Ties are broken by ID
+	Order       SortOrder
+	Page        int
+	PageSize    int
+	After       *cursor.Cursor // Return the users after this cursor instead of a page
+}
+
+// Matches reports whether the user passes the filters, ignoring pagination
+func (o *UserListOptions) Matches(user *User) bool {
+	if o.Search != "" {
+		search := strings.ToLower(o.Search)
+		match := strings.Contains
+		if o.Prefix {
+			match = strings.HasPrefix
+		}
+		if !match(strings.ToLower(user.Name), search) && !match(strings.ToLower(user.Email), search) {
+			return false
+		}
+	}
+	if !o.CreatedFrom.IsZero() && user.CreatedAt.Before(o.CreatedFrom) {
+		return false
+	}
+	if !o.CreatedTo.IsZero() && !user.CreatedAt.Before(o.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// Less reports whether user a comes before user b in the sort order
+func (o *UserListOptions) Less(a, b *User) bool {
+	var cmp int
+	switch o.SortBy {
+	case UserSortName:
+		cmp = strings.Compare(a.Name, b.Name)
+	case UserSortEmail:
+		cmp = strings.Compare(a.Email, b.Email)
+	default:
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			cmp = -1
+		case a.CreatedAt.After(b.CreatedAt):
+			cmp = 1
+		}
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+
+	if o.Order == SortAsc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// Follows reports whether the user comes after the cursor in the sort order.
+// Every user follows when no cursor is set.
+func (o *UserListOptions) Follows(user *User) bool {
+	return o.After == nil || o.After.Precedes(user.CreatedAt, user.ID, o.Order != SortAsc)
+}
+
+// NextCursor returns the cursor of the page after the given one, or an empty string
+// if the page is the last one or users are not sorted by creation time
+func (o *UserListOptions) NextCursor(users []*User) string {
+	if o.SortBy != UserSortCreatedAt || len(users) == 0 || len(users) < o.PageSize {
+		return ""
+	}
+	last := users[len(users)-1]
+	return cursor.New(last.CreatedAt, last.ID).Encode()
+}
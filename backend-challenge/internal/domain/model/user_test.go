@@ -14,7 +14,7 @@ func TestNewUser(t *testing.T) {
 	}
 
 	// Create user
-	user, err := NewUser(input)
+	user, err := NewUser(input, DefaultPasswordHasher())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -111,4 +111,15 @@ func TestUpdate(t *testing.T) {
 	if user.Email != input.Email {
 		t.Errorf("Expected email %s, got %s", input.Email, user.Email)
 	}
+
+	// Test profile fields are set when given and cleared when empty
+	bio, timezone, empty := "Hello", "Asia/Bangkok", ""
+	user.Update(&UpdateUserInput{Bio: &bio, Timezone: &timezone})
+	if user.Profile.Bio != bio || user.Profile.Timezone != timezone {
+		t.Errorf("Expected profile to be updated, got %+v", user.Profile)
+	}
+	user.Update(&UpdateUserInput{Bio: &empty})
+	if user.Profile.Bio != "" || user.Profile.Timezone != timezone {
+		t.Errorf("Expected only bio to be cleared, got %+v", user.Profile)
+	}
 }
\ No newline at end of file
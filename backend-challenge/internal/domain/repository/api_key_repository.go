@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	// Create stores a new API key
+	Create(ctx context.Context, key *model.APIKey) error
+
+	// GetByHash fetches an API key by the hash of its value
+	GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+
+	// ListByUser returns the API keys of a user, newest first
+	ListByUser(ctx context.Context, userID string) ([]*model.APIKey, error)
+
+	// Revoke revokes an API key of a user. It returns false if the user has no such active key.
+	Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error)
+
+	// UpdateLastUsed records when an API key was last used
+	UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+
+	// DeleteByUser removes every API key of a user
+	DeleteByUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// AuditRepository defines the interface for audit log data access.
+// The log is append-only: events cannot be deleted, and are only changed to
+// anonymize the trail of an erased user.
+type AuditRepository interface {
+	// Append records a new event
+	Append(ctx context.Context, event *model.AuditEvent) error
+
+	// List returns the events matching the filter, newest first, with the total number of matches
+	List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error)
+
+	// AnonymizeUser replaces the ID of a user with a pseudonym in every event they took
+	// part in, and removes the client details and email recorded with those events
+	AnonymizeUser(ctx context.Context, userID, pseudonym string) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// LoginAttemptRepository defines the interface for failed login counters
+type LoginAttemptRepository interface {
+	// Get returns the failed login counter for a key, or nil if there were no recent failures
+	Get(ctx context.Context, key string, now time.Time) (*model.LoginAttempts, error)
+
+	// RecordFailure atomically increments the counter for a key and returns it.
+	// The counter is forgotten once expiresAt has passed without further failures.
+	RecordFailure(ctx context.Context, key string, now, expiresAt time.Time) (*model.LoginAttempts, error)
+
+	// Release atomically decrements the counter for a key, for an attempt that was
+	// counted up front and turned out to succeed
+	Release(ctx context.Context, key string) error
+
+	// Reset clears the counter for a key
+	Reset(ctx context.Context, key string) error
+}
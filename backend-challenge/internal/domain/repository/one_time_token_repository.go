@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// OneTimeTokenRepository defines the interface for one-time token data access
+type OneTimeTokenRepository interface {
+	// Create stores a new one-time token
+	Create(ctx context.Context, token *model.OneTimeToken) error
+
+	// GetValid fetches an unused, unexpired token with the given purpose and hash without consuming it
+	GetValid(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error)
+
+	// Consume marks an unused, unexpired token with the given purpose and hash as used
+	// and returns it. Only one caller can consume a token.
+	Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error)
+
+	// DeleteByUser removes every token of a user with the given purpose
+	DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error
+}
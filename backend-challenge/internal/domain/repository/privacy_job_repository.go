@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// PrivacyJobRepository defines the interface for data export and erasure job access
+type PrivacyJobRepository interface {
+	// Create stores a new job
+	Create(ctx context.Context, job *model.PrivacyJob) error
+
+	// GetByID fetches a job by ID
+	GetByID(ctx context.Context, id string) (*model.PrivacyJob, error)
+
+	// GetLatest returns the most recent job of a type for a user, or nil if there is none
+	GetLatest(ctx context.Context, userID string, jobType model.PrivacyJobType) (*model.PrivacyJob, error)
+
+	// ClaimNext marks the oldest pending job as running and returns it, or nil if there is
+	// none. Running jobs started before staleBefore are claimed again, as their worker
+	// has stopped. Only one caller can claim a job.
+	ClaimNext(ctx context.Context, now, staleBefore time.Time) (*model.PrivacyJob, error)
+
+	// Update saves the status, result and timestamps of a job
+	Update(ctx context.Context, job *model.PrivacyJob) error
+
+	// DeleteExports removes every export job of a user, with their archives
+	DeleteExports(ctx context.Context, userID string) error
+}
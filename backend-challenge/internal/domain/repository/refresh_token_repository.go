@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// RefreshTokenRepository defines the interface for refresh token data access
+type RefreshTokenRepository interface {
+	// Create stores a new refresh token
+	Create(ctx context.Context, token *model.RefreshToken) error
+
+	// GetByHash fetches a refresh token by the hash of its value
+	GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+
+	// MarkUsed marks an unused refresh token as used. It returns false if the token
+	// had already been used, so that concurrent rotations can be detected.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+
+	// RevokeFamily revokes every refresh token belonging to a token family
+	RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+
+	// RevokeByUser revokes every refresh token belonging to a user
+	RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error
+
+	// DeleteByUser removes every refresh token of a user
+	DeleteByUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// SessionRepository defines the interface for login session data access
+type SessionRepository interface {
+	// Create stores a new session
+	Create(ctx context.Context, session *model.Session) error
+
+	// GetByID fetches a session by ID
+	GetByID(ctx context.Context, id string) (*model.Session, error)
+
+	// ListByUser returns every session of a user, including revoked and expired ones, newest first
+	ListByUser(ctx context.Context, userID string) ([]*model.Session, error)
+
+	// ListActiveByUser returns the active sessions of a user, most recently seen first
+	ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.Session, error)
+
+	// Touch records activity on a session, optionally extending its expiry
+	Touch(ctx context.Context, id, ip string, seenAt, expiresAt time.Time) error
+
+	// Revoke revokes a session of a user. It returns false if the user has no such active session.
+	Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error)
+
+	// RevokeByUser revokes every session of a user
+	RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error
+
+	// DeleteByUser removes every session of a user
+	DeleteByUser(ctx context.Context, userID string) error
+}
@@ -2,36 +2,48 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"backend-challenge/internal/domain/model"
 )
 
-// TodoRepository defines the interface for todo data access
+// TodoRepository defines the interface for todo data access.
+// Items are scoped by owner: items of other owners are never found.
 type TodoRepository interface {
 	// Create creates a new todo item in the database
 	Create(ctx context.Context, todo *model.TodoItem) error
 
-	// GetByID fetches a todo item by ID
-	GetByID(ctx context.Context, id string) (*model.TodoItem, error)
+	// GetByID fetches a todo item of an owner by ID
+	GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error)
 
-	// Update updates a todo item in the database
-	Update(ctx context.Context, todo *model.TodoItem) error
+	// Update saves a todo item of its owner if it was not changed since it was read,
+	// as a single conditional update on its version, and increments the version.
+	// It reports whether the item was saved.
+	Update(ctx context.Context, todo *model.TodoItem) (bool, error)
 
-	// Delete removes a todo item from the database
-	Delete(ctx context.Context, id string) error
+	// Delete removes a todo item of an owner from the database
+	Delete(ctx context.Context, ownerID, id string) error
 
-	// List returns all todo items
-	List(ctx context.Context) ([]*model.TodoItem, error)
+	// DeleteByOwner removes every todo item of an owner
+	DeleteByOwner(ctx context.Context, ownerID string) error
+
+	// List returns all todo items of an owner
+	List(ctx context.Context, ownerID string) ([]*model.TodoItem, error)
 	
-	// FindByStatus returns all todo items with a specific status
-	FindByStatus(ctx context.Context, status model.ItemStatus) ([]*model.TodoItem, error)
+	// FindByStatus returns all todo items of an owner with a specific status
+	FindByStatus(ctx context.Context, ownerID string, status model.ItemStatus) ([]*model.TodoItem, error)
 	
-	// FindByTypeAndStatus returns all todo items with a specific type and status
-	FindByTypeAndStatus(ctx context.Context, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error)
+	// FindByTypeAndStatus returns all todo items of an owner with a specific type and status
+	FindByTypeAndStatus(ctx context.Context, ownerID string, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error)
 	
-	// UpdateStatus updates the status of a todo item
-	UpdateStatus(ctx context.Context, id string, status model.ItemStatus) error
+	// UpdateStatus updates the status of a todo item of an owner
+	UpdateStatus(ctx context.Context, ownerID, id string, status model.ItemStatus) error
 	
-	// FindToReturn finds all todo items that should be returned to the main list
-	FindToReturn(ctx context.Context, currentTime string) ([]*model.TodoItem, error)
-}
\ No newline at end of file
+	// FindScheduledReturns returns the todo items of every owner waiting in their type column
+	FindScheduledReturns(ctx context.Context) ([]*model.TodoItem, error)
+
+	// ReturnIfDue moves a todo item back to the main list if it is still in its type column
+	// and its return time is not after now, as a single conditional update. It reports
+	// whether the item was returned.
+	ReturnIfDue(ctx context.Context, id string, now time.Time) (bool, error)
+}
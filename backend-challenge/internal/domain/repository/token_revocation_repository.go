@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// TokenRevocationRepository defines the interface for the access token revocation list
+type TokenRevocationRepository interface {
+	// RevokeToken adds a single token to the revocation list
+	RevokeToken(ctx context.Context, token *model.RevokedToken) error
+
+	// IsTokenRevoked checks whether a token ID is on the revocation list
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+
+	// RevokeUserTokens revokes every token of a user issued at or before the given time
+	RevokeUserTokens(ctx context.Context, userID string, revokedBefore time.Time) error
+
+	// GetUserRevocation returns the time before which the user's tokens are revoked.
+	// It returns the zero time if the user has no revocation.
+	GetUserRevocation(ctx context.Context, userID string) (time.Time, error)
+}
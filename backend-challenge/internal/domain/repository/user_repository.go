@@ -2,15 +2,21 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"backend-challenge/internal/domain/model"
 )
 
-// UserRepository defines the interface for user data access
+// UserRepository defines the interface for user data access.
+// Soft deleted users are hidden from every query until they are restored.
 type UserRepository interface {
 	// Create creates a new user in the database
 	Create(ctx context.Context, user *model.User) error
 
+	// CreateMany creates users in one batch. Users whose email is already taken are
+	// skipped and their indexes returned, the others are still created.
+	CreateMany(ctx context.Context, users []*model.User) ([]int, error)
+
 	// GetByID fetches a user by ID
 	GetByID(ctx context.Context, id string) (*model.User, error)
 
@@ -20,11 +26,26 @@ type UserRepository interface {
 	// Update updates a user in the database
 	Update(ctx context.Context, user *model.User) error
 
-	// Delete removes a user from the database
-	Delete(ctx context.Context, id string) error
+	// UpdateMFA replaces the two-factor state of a user if it still equals previous.
+	// It returns false if the state was changed in the meantime or the user does not exist.
+	UpdateMFA(ctx context.Context, id string, previous, mfa model.MFA) (bool, error)
+
+	// Delete soft deletes a user. The email stays taken until the user is purged.
+	Delete(ctx context.Context, id string, deletedAt time.Time) error
+
+	// Restore undoes the soft deletion of a user
+	Restore(ctx context.Context, id string) error
+
+	// Erase permanently removes a user, whether or not they are soft deleted.
+	// It returns false if the user does not exist, for example because it was already erased.
+	Erase(ctx context.Context, id string) (bool, error)
+
+	// ListDeleted returns the users soft deleted before the given time
+	ListDeleted(ctx context.Context, before time.Time) ([]*model.User, error)
 
-	// List returns all users with pagination
-	List(ctx context.Context, page, pageSize int) ([]*model.User, error)
+	// List returns a page of the users matching the options with the total number of matches.
+	// The total is only counted for offset pages and is zero for pages after a cursor.
+	List(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error)
 
 	// CountUsers returns the total number of users in the database
 	CountUsers(ctx context.Context) (int64, error)
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+	"backend-challenge/pkg/securetoken"
+)
+
+const (
+	// apiKeyPrefix marks API keys so that they are easy to recognise, for example by secret scanners
+	apiKeyPrefix = "bck_"
+
+	// apiKeyLastUsedInterval limits how often the last used time is written
+	apiKeyLastUsedInterval = time.Minute
+
+	// maxAPIKeysPerUser limits the number of active keys a user can hold
+	maxAPIKeysPerUser = 25
+)
+
+// APIKeyService defines the API key business logic service
+type APIKeyService interface {
+	// Create creates a new API key for a user. The returned key is only shown once.
+	Create(ctx context.Context, userID string, input *model.CreateAPIKeyInput) (*model.CreatedAPIKey, error)
+
+	// List returns the API keys of a user
+	List(ctx context.Context, userID string) ([]*model.APIKey, error)
+
+	// Revoke revokes an API key of a user
+	Revoke(ctx context.Context, userID, id string) error
+
+	// Verify checks an API key and returns it together with its owner
+	Verify(ctx context.Context, key string) (*model.APIKey, *model.User, error)
+}
+
+// apiKeyService implements APIKeyService
+type apiKeyService struct {
+	repo     repository.APIKeyRepository
+	userRepo repository.UserRepository
+	audit    AuditLogger
+}
+
+// APIKeyServiceOption configures optional features of the API key service
+type APIKeyServiceOption func(*apiKeyService)
+
+// WithAPIKeyAuditLog records the creation and revocation of API keys
+func WithAPIKeyAuditLog(audit AuditLogger) APIKeyServiceOption {
+	return func(s *apiKeyService) {
+		s.audit = audit
+	}
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(repo repository.APIKeyRepository, userRepo repository.UserRepository, opts ...APIKeyServiceOption) APIKeyService {
+	s := &apiKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create creates a new API key for a user. The returned key is only shown once.
+func (s *apiKeyService) Create(ctx context.Context, userID string, input *model.CreateAPIKeyInput) (*model.CreatedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if len(input.Scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, scope := range input.Scopes {
+		if !model.IsValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	now := time.Now()
+	if !input.ExpiresAt.IsZero() && !input.ExpiresAt.After(now) {
+		return nil, ErrInvalidAPIKeyExpiry
+	}
+
+	// Limit the number of active keys
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, key := range keys {
+		if key.IsActive(now) {
+			active++
+		}
+	}
+	if active >= maxAPIKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	// The key is made of a public prefix, used in listings, and a secret part
+	prefix, err := securetoken.New(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := securetoken.New(securetoken.DefaultSize)
+	if err != nil {
+		return nil, err
+	}
+	prefix = apiKeyPrefix + prefix
+	value := prefix + "_" + secret
+
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   securetoken.Hash(value),
+		Scopes:    input.Scopes,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	// Save to repository
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, model.AuditAPIKeyCreated, userID, map[string]string{
+		"key_id": key.ID,
+		"prefix": key.Prefix,
+		"scopes": strings.Join(key.Scopes, ","),
+	})
+
+	return &model.CreatedAPIKey{APIKey: key, Key: value}, nil
+}
+
+// List returns the API keys of a user
+func (s *apiKeyService) List(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Revoke revokes an API key of a user
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id string) error {
+	if id == "" {
+		return ErrInvalidID
+	}
+
+	revoked, err := s.repo.Revoke(ctx, id, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	s.record(ctx, model.AuditAPIKeyRevoked, userID, map[string]string{"key_id": id})
+
+	return nil
+}
+
+// record records an audit event for the key owner if the audit log is enabled
+func (s *apiKeyService) record(ctx context.Context, action model.AuditAction, userID string, details map[string]string) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:   action,
+		TargetID: userID,
+		Details:  details,
+	})
+}
+
+// Verify checks an API key and returns it together with its owner
+func (s *apiKeyService) Verify(ctx context.Context, value string) (*model.APIKey, *model.User, error) {
+	if !strings.HasPrefix(value, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByHash(ctx, securetoken.Hash(value))
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	// Avoid a write on every request
+	if now.Sub(key.LastUsedAt) >= apiKeyLastUsedInterval {
+		if err := s.repo.UpdateLastUsed(ctx, key.ID, now); err != nil {
+			return nil, nil, err
+		}
+		key.LastUsedAt = now
+	}
+
+	return key, user, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock APIKeyRepository for testing
+type mockAPIKeyRepository struct {
+	keys []*model.APIKey
+}
+
+func (m *mockAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	key.ID = "key-" + time.Now().Format(time.RFC3339Nano)
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *mockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return nil, errors.New("API key not found")
+}
+
+func (m *mockAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepository) Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error) {
+	for _, key := range m.keys {
+		if key.ID == id && key.UserID == userID && key.RevokedAt.IsZero() {
+			key.RevokedAt = revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockAPIKeyRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	for _, key := range m.keys {
+		if key.ID == id {
+			key.LastUsedAt = usedAt
+		}
+	}
+	return nil
+}
+
+func (m *mockAPIKeyRepository) DeleteByUser(ctx context.Context, userID string) error {
+	var kept []*model.APIKey
+	for _, key := range m.keys {
+		if key.UserID != userID {
+			kept = append(kept, key)
+		}
+	}
+	m.keys = kept
+	return nil
+}
+
+// Test APIKeyService
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service
+	users := newMockUserRepository()
+	audit := &mockAuditRepository{}
+	service := NewAPIKeyService(&mockAPIKeyRepository{}, users, WithAPIKeyAuditLog(NewAuditService(audit)))
+	user, _ := NewUserService(users).Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+
+	// Test case: invalid scope
+	_, err := service.Create(ctx, user.ID, &model.CreateAPIKeyInput{Name: "script", Scopes: []string{"admin"}})
+	if err != ErrInvalidScope {
+		t.Errorf("Expected error %v, got %v", ErrInvalidScope, err)
+	}
+
+	// Test case: expiry in the past
+	_, err = service.Create(ctx, user.ID, &model.CreateAPIKeyInput{
+		Name:      "script",
+		Scopes:    []string{model.ScopeTodosRead},
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	if err != ErrInvalidAPIKeyExpiry {
+		t.Errorf("Expected error %v, got %v", ErrInvalidAPIKeyExpiry, err)
+	}
+
+	// Test case: successful creation
+	created, err := service.Create(ctx, user.ID, &model.CreateAPIKeyInput{Name: "script", Scopes: []string{model.ScopeTodosRead}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix+"_") {
+		t.Errorf("Expected key to start with prefix %s, got %s", created.Prefix, created.Key)
+	}
+	if created.KeyHash == "" || strings.Contains(created.KeyHash, created.Key) {
+		t.Errorf("Expected only a hash of the key to be stored")
+	}
+
+	// Test case: verification returns the key and owner
+	key, owner, err := service.Verify(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if owner.ID != user.ID || !key.HasScope(model.ScopeTodosRead) || key.HasScope(model.ScopeTodosWrite) {
+		t.Errorf("Unexpected key %+v for owner %s", key, owner.ID)
+	}
+	if key.LastUsedAt.IsZero() {
+		t.Errorf("Expected last used time to be recorded")
+	}
+
+	// Test case: unknown key
+	if _, _, err := service.Verify(ctx, created.Prefix+"_wrong"); err != ErrInvalidAPIKey {
+		t.Errorf("Expected error %v, got %v", ErrInvalidAPIKey, err)
+	}
+
+	// Test case: other users cannot revoke the key
+	if err := service.Revoke(ctx, "other-user", created.ID); err != ErrAPIKeyNotFound {
+		t.Errorf("Expected error %v, got %v", ErrAPIKeyNotFound, err)
+	}
+
+	// Test case: revoked keys are rejected
+	if err := service.Revoke(ctx, user.ID, created.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := service.Verify(ctx, created.Key); err != ErrInvalidAPIKey {
+		t.Errorf("Expected error %v, got %v", ErrInvalidAPIKey, err)
+	}
+
+	// Test case: creation and revocation are audited, failed attempts are not
+	if len(audit.events) != 2 {
+		t.Fatalf("Expected 2 audit events, got %d", len(audit.events))
+	}
+	if audit.events[0].Action != model.AuditAPIKeyCreated || audit.events[0].Details["key_id"] != created.ID {
+		t.Errorf("Unexpected event %+v", audit.events[0])
+	}
+	if audit.events[1].Action != model.AuditAPIKeyRevoked || audit.events[1].TargetID != user.ID {
+		t.Errorf("Unexpected event %+v", audit.events[1])
+	}
+}
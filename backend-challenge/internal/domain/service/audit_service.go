@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+)
+
+// AuditLogger records security-relevant events
+type AuditLogger interface {
+	// Record records an event, filling in the actor and client of the request.
+	// A failed write is logged and never fails the audited operation.
+	Record(ctx context.Context, event *model.AuditEvent)
+}
+
+// AuditService records and queries the audit log
+type AuditService interface {
+	AuditLogger
+
+	// List returns the events matching the filter, newest first, with the total number of matches
+	List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error)
+}
+
+// auditService implements AuditService
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+// Record records an event, filling in the actor and client of the request
+func (s *auditService) Record(ctx context.Context, event *model.AuditEvent) {
+	if event.ActorID == "" {
+		if actor, ok := model.PrincipalFromContext(ctx); ok {
+			event.ActorID = actor.UserID
+		}
+	}
+
+	client := model.ClientInfoFromContext(ctx)
+	if event.IP == "" {
+		event.IP = client.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	if err := s.repo.Append(ctx, event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// List returns the events matching the filter, newest first, with the total number of matches
+func (s *auditService) List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error) {
+	// Validate pagination parameters
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	return s.repo.List(ctx, filter)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock AuditRepository for testing
+type mockAuditRepository struct {
+	events []*model.AuditEvent
+}
+
+func (m *mockAuditRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *mockAuditRepository) List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error) {
+	var matches []*model.AuditEvent
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if filter.Matches(m.events[i]) {
+			matches = append(matches, m.events[i])
+		}
+	}
+	return matches, int64(len(matches)), nil
+}
+
+func (m *mockAuditRepository) AnonymizeUser(ctx context.Context, userID, pseudonym string) error {
+	for _, event := range m.events {
+		event.Anonymize(userID, pseudonym)
+	}
+	return nil
+}
+
+// Test Record fills in the actor and client of the request
+func TestAuditRecord(t *testing.T) {
+	repo := &mockAuditRepository{}
+	audit := NewAuditService(repo)
+
+	ctx := model.WithPrincipal(context.Background(), model.Principal{UserID: "admin-1", Role: model.RoleAdmin})
+	ctx = model.WithClientInfo(ctx, model.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"})
+
+	audit.Record(ctx, &model.AuditEvent{Action: model.AuditUserDeleted, TargetID: "user-1"})
+
+	if len(repo.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(repo.events))
+	}
+	event := repo.events[0]
+	if event.ActorID != "admin-1" {
+		t.Errorf("Expected actor admin-1, got %q", event.ActorID)
+	}
+	if event.IP != "203.0.113.7" || event.UserAgent != "curl/8.0" {
+		t.Errorf("Expected client info to be recorded, got %q %q", event.IP, event.UserAgent)
+	}
+	if event.CreatedAt.IsZero() {
+		t.Error("Expected creation time to be set")
+	}
+
+	// An explicit actor is kept
+	audit.Record(ctx, &model.AuditEvent{Action: model.AuditLoginSucceeded, ActorID: "user-2", TargetID: "user-2"})
+	if repo.events[1].ActorID != "user-2" {
+		t.Errorf("Expected actor user-2, got %q", repo.events[1].ActorID)
+	}
+}
+
+// Test AuditFilter matches by action, actor, target and time range
+func TestAuditFilterMatches(t *testing.T) {
+	now := time.Now()
+	event := &model.AuditEvent{
+		Action:    model.AuditLoginFailed,
+		ActorID:   "user-1",
+		TargetID:  "user-1",
+		CreatedAt: now,
+	}
+
+	tests := []struct {
+		name   string
+		filter model.AuditFilter
+		want   bool
+	}{
+		{"empty filter", model.AuditFilter{}, true},
+		{"same action", model.AuditFilter{Action: model.AuditLoginFailed}, true},
+		{"other action", model.AuditFilter{Action: model.AuditLoginSucceeded}, false},
+		{"other actor", model.AuditFilter{ActorID: "user-2"}, false},
+		{"other target", model.AuditFilter{TargetID: "user-2"}, false},
+		{"from is inclusive", model.AuditFilter{From: now}, true},
+		{"to is exclusive", model.AuditFilter{To: now}, false},
+		{"within range", model.AuditFilter{From: now.Add(-time.Minute), To: now.Add(time.Minute)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test the user service records logins, failures and account changes
+func TestUserServiceAudit(t *testing.T) {
+	repo := newMockUserRepository()
+	auditRepo := &mockAuditRepository{}
+	service := NewUserService(repo, WithAuditLog(NewAuditService(auditRepo)))
+
+	ctx := context.Background()
+	user, err := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Failed and successful login
+	_, _ = service.Login(ctx, &model.LoginUserInput{Email: "test@example.com", Password: "wrongpassword"})
+	_, _ = service.Login(ctx, &model.LoginUserInput{Email: "nobody@example.com", Password: "password123"})
+	if _, err := service.Login(ctx, &model.LoginUserInput{Email: "test@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Delete as admin
+	adminCtx := model.WithPrincipal(ctx, model.Principal{UserID: "admin-1", Role: model.RoleAdmin})
+	if err := service.DeleteUser(adminCtx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := []struct {
+		action model.AuditAction
+		actor  string
+		target string
+		reason string
+	}{
+		{model.AuditUserRegistered, user.ID, user.ID, ""},
+		{model.AuditLoginFailed, "", user.ID, "invalid_password"},
+		{model.AuditLoginFailed, "", "", "unknown_email"},
+		{model.AuditLoginSucceeded, user.ID, user.ID, ""},
+		{model.AuditUserDeleted, "admin-1", user.ID, ""},
+	}
+	if len(auditRepo.events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(auditRepo.events))
+	}
+	for i, w := range want {
+		event := auditRepo.events[i]
+		if event.Action != w.action || event.ActorID != w.actor || event.TargetID != w.target {
+			t.Errorf("Event %d: expected %s by %q on %q, got %s by %q on %q",
+				i, w.action, w.actor, w.target, event.Action, event.ActorID, event.TargetID)
+		}
+		if w.reason != "" && event.Details["reason"] != w.reason {
+			t.Errorf("Event %d: expected reason %q, got %q", i, w.reason, event.Details["reason"])
+		}
+	}
+}
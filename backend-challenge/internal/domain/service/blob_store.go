@@ -0,0 +1,15 @@
+package service
+
+import "context"
+
+// BlobStore keeps binary objects such as uploaded images under slash-separated keys
+type BlobStore interface {
+	// Put stores data under a key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get returns the data stored under a key, or ErrBlobNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete removes the object stored under a key. Missing objects are ignored.
+	Delete(ctx context.Context, key string) error
+}
@@ -5,13 +5,69 @@ import "errors"
 // Common errors shared across services
 var (
 	// User related errors
-	ErrUserNotFound    = errors.New("user not found")
-	ErrEmailExists     = errors.New("email already exists")
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrEmailExists      = errors.New("email already exists")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrWeakPassword     = errors.New("password does not meet requirements")
+	ErrEmailNotVerified = errors.New("email address has not been verified")
+	ErrInvalidSortField = errors.New("invalid sort field")
+	ErrInvalidSortOrder = errors.New("invalid sort order")
+	ErrCursorSortField  = errors.New("cursor pagination requires sorting by created_at")
+	
+	// Profile related errors
+	ErrInvalidDisplayName = errors.New("display name must be at most 100 characters")
+	ErrInvalidLocale      = errors.New("locale must be a language tag such as en or th-TH")
+	ErrInvalidTimezone    = errors.New("timezone must be an IANA time zone such as Asia/Bangkok")
+	ErrInvalidBio         = errors.New("bio must be at most 500 characters")
+	
+	// Avatar related errors
+	ErrAvatarNotFound        = errors.New("avatar not found")
+	ErrInvalidAvatar         = errors.New("avatar is not a valid image")
+	ErrUnsupportedAvatarType = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrAvatarTooLarge        = errors.New("avatar is too large")
+	ErrAvatarsNotConfigured  = errors.New("avatar storage is not configured")
+	ErrBlobNotFound          = errors.New("blob not found")
+	
+	// Bulk import related errors
+	ErrTooManyImportRows    = errors.New("too many rows in import")
+	ErrDuplicateImportEmail = errors.New("email appears more than once in import")
+	
+	// Login related errors
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+	
+	// Two-factor authentication related errors
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment has not been started")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+	
+	// Email token related errors
+	ErrInvalidResetToken        = errors.New("invalid or expired reset token")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrMailNotConfigured        = errors.New("email delivery is not configured")
+	
+	// API key related errors
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrInvalidAPIKey       = errors.New("invalid API key")
+	ErrInvalidAPIKeyName   = errors.New("API key name must be between 1 and 100 characters")
+	ErrInvalidAPIKeyExpiry = errors.New("API key expiry must be in the future")
+	ErrInvalidScope        = errors.New("invalid API key scope")
+	ErrInsufficientScope   = errors.New("API key does not grant the required scope")
+	ErrTooManyAPIKeys      = errors.New("too many active API keys")
+	
+	// Privacy request related errors
+	ErrPrivacyJobNotFound = errors.New("privacy request not found")
 	
 	// Todo related errors
-	ErrTodoNotFound    = errors.New("todo item not found")
+	ErrTodoNotFound       = errors.New("todo item not found")
+	ErrInvalidReturnAfter = errors.New("return_after must be between 0s and 24h")
+	ErrInvalidTodoType    = errors.New("type must be Fruit or Vegetable")
+	ErrTodoAlreadyInMain  = errors.New("todo item is already in the main list")
+	ErrTodoConflict       = errors.New("todo item was changed by another request, try again")
 	
 	// Shared errors
 	ErrInvalidID       = errors.New("invalid ID")
+	ErrForbidden       = errors.New("forbidden")
 )
\ No newline at end of file
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"backend-challenge/internal/domain/repository"
+)
+
+// LoginLockedError is returned while further login attempts are blocked.
+// It matches ErrTooManyLoginAttempts with errors.Is.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the error message
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+// Unwrap returns ErrTooManyLoginAttempts
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottleConfig configures login throttling. After the free attempts, every
+// further failure doubles the time the client has to wait, starting at BaseDelay
+// and capped at MaxDelay. Counters are forgotten after Window without failures.
+type LoginThrottleConfig struct {
+	EmailFreeAttempts int
+	IPFreeAttempts    int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	Window            time.Duration
+}
+
+// DefaultLoginThrottleConfig returns the default login throttling configuration
+func DefaultLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		EmailFreeAttempts: 5,
+		IPFreeAttempts:    20,
+		BaseDelay:         time.Second,
+		MaxDelay:          15 * time.Minute,
+		Window:            time.Hour,
+	}
+}
+
+// LoginThrottler limits failed login attempts per account and per client IP.
+// Every attempt is counted as a failure before the credentials are checked, so
+// that concurrent requests cannot slip past the limit.
+type LoginThrottler interface {
+	// Reserve counts an attempt for the email and IP. It returns a *LoginLockedError
+	// if the email or IP is currently locked out. The attempt stays counted as a
+	// failure unless RecordSuccess is called.
+	Reserve(ctx context.Context, email, ip string) error
+
+	// RecordSuccess clears the failed logins of the email and releases the
+	// attempt reserved for the IP
+	RecordSuccess(ctx context.Context, email, ip string) error
+}
+
+// loginThrottler implements LoginThrottler
+type loginThrottler struct {
+	repo   repository.LoginAttemptRepository
+	config LoginThrottleConfig
+}
+
+// NewLoginThrottler creates a new LoginThrottler
+func NewLoginThrottler(repo repository.LoginAttemptRepository, config LoginThrottleConfig) LoginThrottler {
+	// Counters must outlive the longest lockout
+	if config.Window < config.MaxDelay {
+		config.Window = config.MaxDelay
+	}
+
+	return &loginThrottler{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// Reserve counts an attempt for the email and IP. It returns a *LoginLockedError
+// if the email or IP is currently locked out.
+func (t *loginThrottler) Reserve(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	keys := t.keys(email, ip)
+
+	// Attempts while locked out are rejected without extending the lockout
+	previous := make([]int, len(keys))
+	var retryAfter time.Duration
+	for i, key := range keys {
+		attempts, err := t.repo.Get(ctx, key.id, now)
+		if err != nil {
+			return err
+		}
+		if attempts == nil {
+			continue
+		}
+
+		previous[i] = attempts.Failures
+		lockedUntil := attempts.LastFailureAt.Add(t.delay(attempts.Failures, key.freeAttempts))
+		if wait := lockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	// The increment is atomic, so only one of several concurrent attempts gets
+	// the next slot once the free attempts are used up
+	for i, key := range keys {
+		attempts, err := t.repo.RecordFailure(ctx, key.id, now, now.Add(t.config.Window))
+		if err != nil {
+			return err
+		}
+
+		if attempts.Failures > key.freeAttempts && attempts.Failures != previous[i]+1 {
+			if wait := t.delay(attempts.Failures, key.freeAttempts); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failed logins of the email. The IP counter is only
+// released, so that logging into one account does not reset guessing against others.
+func (t *loginThrottler) RecordSuccess(ctx context.Context, email, ip string) error {
+	if err := t.repo.Reset(ctx, emailKey(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return t.repo.Release(ctx, "ip:"+ip)
+}
+
+// throttleKey is a counter key with the failures it allows before backing off
+type throttleKey struct {
+	id           string
+	freeAttempts int
+}
+
+// keys returns the counter keys for a login attempt
+func (t *loginThrottler) keys(email, ip string) []throttleKey {
+	keys := []throttleKey{{id: emailKey(email), freeAttempts: t.config.EmailFreeAttempts}}
+	if ip != "" {
+		keys = append(keys, throttleKey{id: "ip:" + ip, freeAttempts: t.config.IPFreeAttempts})
+	}
+	return keys
+}
+
+// delay returns how long to wait after the last failure
+func (t *loginThrottler) delay(failures, freeAttempts int) time.Duration {
+	if failures < freeAttempts {
+		return 0
+	}
+
+	delay := t.config.BaseDelay
+	for i := freeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= t.config.MaxDelay {
+			return t.config.MaxDelay
+		}
+	}
+	if delay > t.config.MaxDelay {
+		return t.config.MaxDelay
+	}
+
+	return delay
+}
+
+// emailKey returns the counter key for an email
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock LoginAttemptRepository for testing
+type mockLoginAttemptRepository struct {
+	attempts map[string]*model.LoginAttempts
+	mu       sync.Mutex
+}
+
+func (m *mockLoginAttemptRepository) Get(ctx context.Context, key string, now time.Time) (*model.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok || !now.Before(attempts.ExpiresAt) {
+		return nil, nil
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+func (m *mockLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now, expiresAt time.Time) (*model.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		attempts = &model.LoginAttempts{Key: key}
+		m.attempts[key] = attempts
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	attempts.ExpiresAt = expiresAt
+	copied := *attempts
+	return &copied, nil
+}
+
+func (m *mockLoginAttemptRepository) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempts, ok := m.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+	}
+	return nil
+}
+
+func (m *mockLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// Test LoginThrottle
+func TestLoginThrottle(t *testing.T) {
+	ctx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "203.0.113.7"})
+
+	// Create service with a strict throttle
+	attempts := &mockLoginAttemptRepository{attempts: make(map[string]*model.LoginAttempts)}
+	throttle := NewLoginThrottler(attempts, LoginThrottleConfig{
+		EmailFreeAttempts: 2,
+		IPFreeAttempts:    4,
+		BaseDelay:         time.Minute,
+		MaxDelay:          time.Hour,
+		Window:            time.Hour,
+	})
+	service := NewUserService(newMockUserRepository(), WithLoginThrottle(throttle))
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		_, err := service.Register(ctx, &model.RegisterUserInput{
+			Name:     "Test User",
+			Email:    email,
+			Password: "password123",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// Test case: success clears the email counter
+	wrong := &model.LoginUserInput{Email: "alice@example.com", Password: "wrongpassword"}
+	if _, err := service.Login(ctx, wrong); err != ErrInvalidCredentials {
+		t.Fatalf("Expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+	if _, err := service.Login(ctx, &model.LoginUserInput{Email: "alice@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: account is locked after the free attempts, even with the right password
+	for i := 0; i < 2; i++ {
+		if _, err := service.Login(ctx, wrong); err != ErrInvalidCredentials {
+			t.Fatalf("Expected error %v, got %v", ErrInvalidCredentials, err)
+		}
+	}
+	_, err := service.Login(ctx, &model.LoginUserInput{Email: "alice@example.com", Password: "password123"})
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > time.Minute {
+		t.Errorf("Expected retry after up to a minute, got %v", locked.RetryAfter)
+	}
+
+	// Test case: the client IP is locked across accounts
+	bob := &model.LoginUserInput{Email: "bob@example.com", Password: "wrongpassword"}
+	if _, err := service.Login(ctx, bob); err != ErrInvalidCredentials {
+		t.Fatalf("Expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+	if _, err := service.Login(ctx, bob); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Errorf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+	}
+
+	// Test case: other clients can still log into other accounts
+	otherCtx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "198.51.100.1"})
+	if _, err := service.Login(otherCtx, &model.LoginUserInput{Email: "bob@example.com", Password: "password123"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// Test case: unknown emails are throttled like existing ones
+	unknown := &model.LoginUserInput{Email: "nobody@example.com", Password: "wrongpassword"}
+	for i := 0; i < 2; i++ {
+		if _, err := service.Login(otherCtx, unknown); err != ErrInvalidCredentials {
+			t.Fatalf("Expected error %v, got %v", ErrInvalidCredentials, err)
+		}
+	}
+	if _, err := service.Login(otherCtx, unknown); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Errorf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+	}
+}
+
+// Test LoginThrottle with concurrent attempts
+func TestLoginThrottleConcurrent(t *testing.T) {
+	ctx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "203.0.113.7"})
+
+	// Create service with a strict throttle
+	attempts := &mockLoginAttemptRepository{attempts: make(map[string]*model.LoginAttempts)}
+	throttle := NewLoginThrottler(attempts, LoginThrottleConfig{
+		EmailFreeAttempts: 2,
+		IPFreeAttempts:    20,
+		BaseDelay:         time.Minute,
+		MaxDelay:          time.Hour,
+		Window:            time.Hour,
+	})
+	service := NewUserService(newMockUserRepository(), WithLoginThrottle(throttle))
+
+	_, err := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "alice@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: concurrent guesses cannot get past the free attempts
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Login(ctx, &model.LoginUserInput{Email: "alice@example.com", Password: "wrongpassword"})
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	checked := 0
+	for err := range results {
+		if err == ErrInvalidCredentials {
+			checked++
+		} else if !errors.Is(err, ErrTooManyLoginAttempts) {
+			t.Errorf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+		}
+	}
+	if checked > 2 {
+		t.Errorf("Expected at most 2 checked passwords, got %d", checked)
+	}
+}
+
+// Test ChangePassword with a LoginThrottle
+func TestChangePasswordThrottle(t *testing.T) {
+	ctx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "203.0.113.7"})
+
+	// Create service with a strict throttle
+	attempts := &mockLoginAttemptRepository{attempts: make(map[string]*model.LoginAttempts)}
+	throttle := NewLoginThrottler(attempts, LoginThrottleConfig{
+		EmailFreeAttempts: 2,
+		IPFreeAttempts:    4,
+		BaseDelay:         time.Minute,
+		MaxDelay:          time.Hour,
+		Window:            time.Hour,
+	})
+	service := NewUserService(newMockUserRepository(), WithLoginThrottle(throttle))
+
+	user, err := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "alice@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: wrong current passwords lock the account like failed logins
+	wrong := &model.ChangePasswordInput{CurrentPassword: "wrongpassword", NewPassword: "newpassword123"}
+	for i := 0; i < 2; i++ {
+		if _, err := service.ChangePassword(ctx, user.ID, wrong); err != ErrInvalidPassword {
+			t.Fatalf("Expected error %v, got %v", ErrInvalidPassword, err)
+		}
+	}
+	right := &model.ChangePasswordInput{CurrentPassword: "password123", NewPassword: "newpassword123"}
+	if _, err := service.ChangePassword(ctx, user.ID, right); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+	}
+
+	// Test case: the lock also applies to logins
+	if _, err := service.Login(ctx, &model.LoginUserInput{Email: user.Email, Password: "password123"}); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Errorf("Expected error %v, got %v", ErrTooManyLoginAttempts, err)
+	}
+}
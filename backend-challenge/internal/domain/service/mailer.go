@@ -0,0 +1,16 @@
+package service
+
+import "context"
+
+// MailMessage represents an email sent to a user
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users
+type Mailer interface {
+	// Send delivers a message
+	Send(ctx context.Context, msg *MailMessage) error
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicyError is returned for passwords that break the password policy.
+// It matches ErrWeakPassword with errors.Is.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+// Error returns the error message listing every broken rule
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error() + ": " + strings.Join(e.Violations, ", ")
+}
+
+// Unwrap returns ErrWeakPassword
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// PasswordPolicyConfig configures the rules new passwords must follow
+type PasswordPolicyConfig struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+
+	// BanUserInfo rejects passwords containing the user's email or name
+	BanUserInfo bool
+	// BannedWords are rejected anywhere in a password, ignoring case
+	BannedWords []string
+}
+
+// DefaultPasswordPolicyConfig returns the default password policy configuration
+func DefaultPasswordPolicyConfig() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:   8,
+		BanUserInfo: true,
+	}
+}
+
+// minBannedPartLength is the length from which parts of a name or email are banned.
+// Shorter parts such as initials would reject too many good passwords.
+const minBannedPartLength = 4
+
+// BreachedPasswordChecker checks passwords against a list of leaked passwords
+type BreachedPasswordChecker interface {
+	// IsBreached reports whether the password appears in the list
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordPolicy checks new passwords on registration, reset and change
+type PasswordPolicy interface {
+	// Check returns a *PasswordPolicyError listing every rule the password breaks.
+	// userInputs are values such as the user's email and name that the password must not contain.
+	Check(ctx context.Context, password string, userInputs ...string) error
+}
+
+// passwordPolicy implements PasswordPolicy
+type passwordPolicy struct {
+	config   PasswordPolicyConfig
+	breached BreachedPasswordChecker
+}
+
+// NewPasswordPolicy creates a new PasswordPolicy. The breached password checker is optional.
+func NewPasswordPolicy(config PasswordPolicyConfig, breached BreachedPasswordChecker) PasswordPolicy {
+	return &passwordPolicy{
+		config:   config,
+		breached: breached,
+	}
+}
+
+// Check returns a *PasswordPolicyError listing every rule the password breaks
+func (p *passwordPolicy) Check(ctx context.Context, password string, userInputs ...string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.config.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if p.config.RequireUppercase && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.config.RequireLowercase && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if p.config.BanUserInfo && containsAny(lowered, bannedParts(userInputs)) {
+		violations = append(violations, "must not contain your name or email")
+	}
+	if containsAny(lowered, p.config.BannedWords) {
+		violations = append(violations, "must not contain a banned word")
+	}
+
+	// Only look up passwords that pass the other rules
+	if len(violations) == 0 && p.breached != nil {
+		breached, err := p.breached.IsBreached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// bannedParts returns the lowercase values and their parts that a password must not contain.
+// Emails only contribute their local part, e.g. "jane.doe@example.com" yields "jane.doe" and "jane".
+func bannedParts(values []string) []string {
+	var parts []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			value = value[:at]
+		}
+		if utf8.RuneCountInString(value) >= minBannedPartLength {
+			parts = append(parts, value)
+		}
+
+		words := strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if utf8.RuneCountInString(word) >= minBannedPartLength {
+				parts = append(parts, word)
+			}
+		}
+	}
+	return parts
+}
+
+// containsAny reports whether s contains any of the non-empty substrings, ignoring case
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		sub = strings.ToLower(strings.TrimSpace(sub))
+		if sub != "" && strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Stub breached password checker with a fixed list
+type stubBreachedPasswords map[string]bool
+
+func (s stubBreachedPasswords) IsBreached(ctx context.Context, password string) (bool, error) {
+	return s[password], nil
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy := NewPasswordPolicy(PasswordPolicyConfig{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		BanUserInfo:      true,
+		BannedWords:      []string{"acme"},
+	}, stubBreachedPasswords{"Password123!": true})
+
+	tests := []struct {
+		name       string
+		password   string
+		violations int
+	}{
+		{"valid password", "Correct-Horse-42", 0},
+		{"too short", "Sh0rt!pw", 1},
+		{"missing classes", "alllowercaseletters", 3},
+		{"contains email", "Jane.Doe-2024!", 1},
+		{"contains name part", "Xsmithsonian-99", 1},
+		{"contains banned word", "Welcome-to-ACME-1", 1},
+		{"breached", "Password123!", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(ctx, tt.password, "jane.doe@example.com", "Jane Smithsonian")
+			if tt.violations == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Expected a policy error, got %v", err)
+			}
+			if !errors.Is(err, ErrWeakPassword) {
+				t.Errorf("Expected error to match %v", ErrWeakPassword)
+			}
+			if len(policyErr.Violations) != tt.violations {
+				t.Errorf("Expected %d violations, got %v", tt.violations, policyErr.Violations)
+			}
+		})
+	}
+
+	// Short name parts such as initials are not banned
+	if err := NewPasswordPolicy(DefaultPasswordPolicyConfig(), nil).Check(ctx, "joyful-river-7", "jo@example.com", "Jo Li"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+	"backend-challenge/pkg/securetoken"
+)
+
+const (
+	// privacyJobTimeout is how long a job can run before another worker claims it again
+	privacyJobTimeout = 10 * time.Minute
+
+	// auditExportPageSize is the number of audit events read at a time during an export
+	auditExportPageSize = 500
+)
+
+// PrivacyService handles data export and erasure requests. Both run as background jobs.
+type PrivacyService interface {
+	// RequestExport starts collecting everything held about a user into an archive.
+	// An export that has not finished yet is returned instead of starting another.
+	RequestExport(ctx context.Context, userID string) (*model.PrivacyJob, error)
+
+	// LatestExport returns the most recent export job of a user, unless its archive has expired
+	LatestExport(ctx context.Context, userID string) (*model.PrivacyJob, error)
+
+	// RequestErasure starts deleting a user and their data, and anonymizing their audit trail.
+	// An erasure that has not finished yet is returned instead of starting another.
+	RequestErasure(ctx context.Context, userID string) (*model.PrivacyJob, error)
+
+	// GetJob returns a job of a user
+	GetJob(ctx context.Context, userID, jobID string) (*model.PrivacyJob, error)
+
+	// RunPendingJobs runs queued jobs until none are left and returns how many ran
+	RunPendingJobs(ctx context.Context) (int, error)
+
+	// PurgeDeletedUsers permanently removes users deleted before the given time together
+	// with their data, and returns how many were removed
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PersonalDataStores are the repositories holding data about users, which is exported
+// and erased together with the user. Repositories left nil are skipped.
+type PersonalDataStores struct {
+	Users         repository.UserRepository
+	Sessions      repository.SessionRepository
+	APIKeys       repository.APIKeyRepository
+	RefreshTokens repository.RefreshTokenRepository
+	OneTimeTokens repository.OneTimeTokenRepository
+	Revocations   repository.TokenRevocationRepository
+	Audit         repository.AuditRepository
+	Avatars       BlobStore
+	Todos         repository.TodoRepository
+}
+
+// privacyService implements PrivacyService
+type privacyService struct {
+	jobs            repository.PrivacyJobRepository
+	stores          PersonalDataStores
+	audit           AuditLogger
+	exportRetention time.Duration
+}
+
+// NewPrivacyService creates a new PrivacyService. Export archives are kept for
+// exportRetention after they are generated. The audit logger may be nil.
+func NewPrivacyService(jobs repository.PrivacyJobRepository, stores PersonalDataStores, audit AuditLogger, exportRetention time.Duration) PrivacyService {
+	return &privacyService{
+		jobs:            jobs,
+		stores:          stores,
+		audit:           audit,
+		exportRetention: exportRetention,
+	}
+}
+
+// RequestExport starts collecting everything held about a user into an archive
+func (s *privacyService) RequestExport(ctx context.Context, userID string) (*model.PrivacyJob, error) {
+	job, created, err := s.request(ctx, userID, model.PrivacyJobExport)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		s.record(ctx, "", model.AuditDataExported, userID)
+	}
+	return job, nil
+}
+
+// LatestExport returns the most recent export job of a user
+func (s *privacyService) LatestExport(ctx context.Context, userID string) (*model.PrivacyJob, error) {
+	job, err := s.jobs.GetLatest(ctx, userID, model.PrivacyJobExport)
+	if err != nil {
+		return nil, err
+	}
+	// Expired archives are only removed from time to time
+	if job == nil || (!job.ExpiresAt.IsZero() && job.ExpiresAt.Before(time.Now())) {
+		return nil, ErrPrivacyJobNotFound
+	}
+	return job, nil
+}
+
+// RequestErasure starts deleting a user and their data
+func (s *privacyService) RequestErasure(ctx context.Context, userID string) (*model.PrivacyJob, error) {
+	job, created, err := s.request(ctx, userID, model.PrivacyJobErasure)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		s.record(ctx, "", model.AuditErasureRequested, userID)
+	}
+	return job, nil
+}
+
+// request queues a job for a user unless one of the same type has not finished yet,
+// and reports whether a job was created
+func (s *privacyService) request(ctx context.Context, userID string, jobType model.PrivacyJobType) (*model.PrivacyJob, bool, error) {
+	if _, err := s.stores.Users.GetByID(ctx, userID); err != nil {
+		return nil, false, ErrUserNotFound
+	}
+
+	latest, err := s.jobs.GetLatest(ctx, userID, jobType)
+	if err != nil {
+		return nil, false, err
+	}
+	if latest != nil && !latest.IsDone() {
+		return latest, false, nil
+	}
+
+	actor, _ := model.PrincipalFromContext(ctx)
+	job := &model.PrivacyJob{
+		Type:        jobType,
+		UserID:      userID,
+		RequestedBy: actor.UserID,
+		Status:      model.PrivacyJobPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, false, err
+	}
+
+	return job, true, nil
+}
+
+// GetJob returns a job of a user
+func (s *privacyService) GetJob(ctx context.Context, userID, jobID string) (*model.PrivacyJob, error) {
+	job, err := s.jobs.GetByID(ctx, jobID)
+	if err != nil || job.UserID != userID {
+		return nil, ErrPrivacyJobNotFound
+	}
+	return job, nil
+}
+
+// RunPendingJobs runs queued jobs until none are left and returns how many ran.
+// A failed job is marked as failed and does not stop the others.
+func (s *privacyService) RunPendingJobs(ctx context.Context) (int, error) {
+	count := 0
+	for {
+		now := time.Now()
+		job, err := s.jobs.ClaimNext(ctx, now, now.Add(-privacyJobTimeout))
+		if err != nil {
+			return count, err
+		}
+		if job == nil {
+			return count, nil
+		}
+
+		switch job.Type {
+		case model.PrivacyJobExport:
+			job.Archive, err = s.collect(ctx, job.UserID)
+		case model.PrivacyJobErasure:
+			err = s.erase(ctx, job)
+		}
+
+		job.CompletedAt = time.Now()
+		if err != nil {
+			job.Status = model.PrivacyJobFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = model.PrivacyJobCompleted
+			if job.Type == model.PrivacyJobExport {
+				job.ExpiresAt = job.CompletedAt.Add(s.exportRetention)
+			}
+		}
+
+		if err := s.jobs.Update(ctx, job); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// collect gathers everything held about a user, leaving out password hashes and other secrets
+func (s *privacyService) collect(ctx context.Context, userID string) (*model.UserDataArchive, error) {
+	user, err := s.stores.Users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	exported := *user
+	exported.Password = ""
+	exported.MFA.Secret = ""
+	exported.MFA.RecoveryCodes = nil
+
+	archive := &model.UserDataArchive{
+		GeneratedAt: time.Now(),
+		User:        &exported,
+		Todos:       []*model.TodoItem{},
+		Sessions:    []*model.Session{},
+		APIKeys:     []*model.APIKey{},
+		AuditEvents: []*model.AuditEvent{},
+	}
+
+	if s.stores.Todos != nil {
+		todos, err := s.stores.Todos.List(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		archive.Todos = append(archive.Todos, todos...)
+	}
+
+	if s.stores.Sessions != nil {
+		if archive.Sessions, err = s.stores.Sessions.ListByUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.stores.APIKeys != nil {
+		keys, err := s.stores.APIKeys.ListByUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			exportedKey := *key
+			exportedKey.KeyHash = ""
+			archive.APIKeys = append(archive.APIKeys, &exportedKey)
+		}
+	}
+
+	if s.stores.Audit != nil {
+		filter := &model.AuditFilter{UserID: userID, Page: 1, PageSize: auditExportPageSize}
+		for {
+			events, _, err := s.stores.Audit.List(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			archive.AuditEvents = append(archive.AuditEvents, events...)
+			if len(events) < auditExportPageSize {
+				break
+			}
+			filter.Page++
+		}
+	}
+
+	return archive, nil
+}
+
+// erase deletes a user and everything held about them, and replaces their ID in the
+// audit trail with a random pseudonym. Each step can safely run again if a job is retried.
+func (s *privacyService) erase(ctx context.Context, job *model.PrivacyJob) error {
+	userID := job.UserID
+
+	// Avatar images are only known from the user, so they go before the account
+	var avatar *model.Avatar
+	if user, err := s.stores.Users.GetByID(ctx, userID); err == nil {
+		avatar = user.Profile.Avatar
+	}
+	if err := s.deleteUserData(ctx, userID, avatar); err != nil {
+		return err
+	}
+	if err := s.jobs.DeleteExports(ctx, userID); err != nil {
+		return err
+	}
+
+	random, err := securetoken.New(12)
+	if err != nil {
+		return err
+	}
+	pseudonym := "erased-" + random
+	if s.stores.Audit != nil {
+		if err := s.stores.Audit.AnonymizeUser(ctx, userID, pseudonym); err != nil {
+			return err
+		}
+	}
+
+	// A retried job finds the account already gone
+	if _, err := s.stores.Users.Erase(ctx, userID); err != nil {
+		return err
+	}
+
+	// Users erasing themselves are only known by the pseudonym from now on
+	actorID := job.RequestedBy
+	if actorID == userID {
+		actorID = pseudonym
+	}
+	s.record(ctx, actorID, model.AuditUserErased, pseudonym)
+
+	return nil
+}
+
+// PurgeDeletedUsers permanently removes users deleted before the given time together
+// with their data. Their audit trail is kept, as they did not ask to be erased.
+func (s *privacyService) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	users, err := s.stores.Users.ListDeleted(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, user := range users {
+		if err := s.deleteUserData(ctx, user.ID, user.Profile.Avatar); err != nil {
+			return purged, err
+		}
+		if err := s.jobs.DeleteExports(ctx, user.ID); err != nil {
+			return purged, err
+		}
+		erased, err := s.stores.Users.Erase(ctx, user.ID)
+		if err != nil {
+			return purged, err
+		}
+		if erased {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// deleteUserData deletes everything held about a user apart from the account itself
+// and their audit trail. The avatar may be nil.
+func (s *privacyService) deleteUserData(ctx context.Context, userID string, avatar *model.Avatar) error {
+	if s.stores.Avatars != nil && avatar != nil {
+		for _, key := range []string{avatar.Key, avatar.ThumbnailKey} {
+			if err := s.stores.Avatars.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Reject access tokens that are still valid. The revocation entry only holds the ID.
+	if s.stores.Revocations != nil {
+		if err := s.stores.Revocations.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if s.stores.Todos != nil {
+		if err := s.stores.Todos.DeleteByOwner(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if s.stores.RefreshTokens != nil {
+		if err := s.stores.RefreshTokens.DeleteByUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if s.stores.Sessions != nil {
+		if err := s.stores.Sessions.DeleteByUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if s.stores.APIKeys != nil {
+		if err := s.stores.APIKeys.DeleteByUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if s.stores.OneTimeTokens != nil {
+		for _, purpose := range []model.TokenPurpose{model.TokenPurposePasswordReset, model.TokenPurposeEmailVerification} {
+			if err := s.stores.OneTimeTokens.DeleteByUser(ctx, userID, purpose); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// record records an audit event for the target user if the audit log is enabled
+func (s *privacyService) record(ctx context.Context, actorID string, action model.AuditAction, targetID string) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+	})
+}
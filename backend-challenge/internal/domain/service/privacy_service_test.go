@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock PrivacyJobRepository for testing
+type mockPrivacyJobRepository struct {
+	jobs []*model.PrivacyJob
+}
+
+func (m *mockPrivacyJobRepository) Create(ctx context.Context, job *model.PrivacyJob) error {
+	job.ID = "job-" + time.Now().Format(time.RFC3339Nano)
+	copied := *job
+	m.jobs = append(m.jobs, &copied)
+	return nil
+}
+
+func (m *mockPrivacyJobRepository) GetByID(ctx context.Context, id string) (*model.PrivacyJob, error) {
+	for _, job := range m.jobs {
+		if job.ID == id {
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("privacy job not found")
+}
+
+func (m *mockPrivacyJobRepository) GetLatest(ctx context.Context, userID string, jobType model.PrivacyJobType) (*model.PrivacyJob, error) {
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		if m.jobs[i].UserID == userID && m.jobs[i].Type == jobType {
+			copied := *m.jobs[i]
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockPrivacyJobRepository) ClaimNext(ctx context.Context, now, staleBefore time.Time) (*model.PrivacyJob, error) {
+	for _, job := range m.jobs {
+		if job.Status == model.PrivacyJobPending {
+			job.Status = model.PrivacyJobRunning
+			job.StartedAt = now
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockPrivacyJobRepository) Update(ctx context.Context, job *model.PrivacyJob) error {
+	for i := range m.jobs {
+		if m.jobs[i].ID == job.ID {
+			copied := *job
+			m.jobs[i] = &copied
+			return nil
+		}
+	}
+	return errors.New("privacy job not found")
+}
+
+func (m *mockPrivacyJobRepository) DeleteExports(ctx context.Context, userID string) error {
+	var kept []*model.PrivacyJob
+	for _, job := range m.jobs {
+		if job.UserID != userID || job.Type != model.PrivacyJobExport {
+			kept = append(kept, job)
+		}
+	}
+	m.jobs = kept
+	return nil
+}
+
+// newPrivacyTestService creates a privacy service with a registered user who has an API key and audit events
+func newPrivacyTestService(t *testing.T) (PrivacyService, *mockPrivacyJobRepository, PersonalDataStores, *model.User) {
+	users := newMockUserRepository()
+	audit := &mockAuditRepository{}
+	keys := &mockAPIKeyRepository{}
+
+	user, err := NewUserService(users, WithAuditLog(NewAuditService(audit))).Register(context.Background(), &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := NewAPIKeyService(keys, users).Create(context.Background(), user.ID, &model.CreateAPIKeyInput{Name: "ci", Scopes: []string{model.ScopeTodosRead}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	jobs := &mockPrivacyJobRepository{}
+	stores := PersonalDataStores{Users: users, APIKeys: keys, Audit: audit}
+	return NewPrivacyService(jobs, stores, NewAuditService(audit), time.Hour), jobs, stores, user
+}
+
+// Test an export job collects the user's data without secrets
+func TestPrivacyExport(t *testing.T) {
+	service, _, _, user := newPrivacyTestService(t)
+	ctx := model.WithPrincipal(context.Background(), model.Principal{UserID: user.ID, Role: model.RoleUser})
+
+	// Test case: there is no export before one is requested
+	if _, err := service.LatestExport(ctx, user.ID); err != ErrPrivacyJobNotFound {
+		t.Errorf("Expected error %v, got %v", ErrPrivacyJobNotFound, err)
+	}
+
+	// Test case: requesting twice returns the pending job
+	job, err := service.RequestExport(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.Status != model.PrivacyJobPending || job.RequestedBy != user.ID {
+		t.Errorf("Unexpected job %+v", job)
+	}
+	again, err := service.RequestExport(ctx, user.ID)
+	if err != nil || again.ID != job.ID {
+		t.Errorf("Expected the pending job %s, got %+v, %v", job.ID, again, err)
+	}
+
+	// Test case: the job runs in the background
+	count, err := service.RunPendingJobs(context.Background())
+	if err != nil || count != 1 {
+		t.Fatalf("Expected 1 job to run, got %d, %v", count, err)
+	}
+	done, err := service.GetJob(ctx, user.ID, job.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if done.Status != model.PrivacyJobCompleted || done.ExpiresAt.IsZero() {
+		t.Errorf("Expected completed job with an expiry, got %+v", done)
+	}
+
+	archive := done.Archive
+	if archive == nil || archive.User.Email != user.Email {
+		t.Fatalf("Expected archive of the user, got %+v", archive)
+	}
+	if archive.User.Password != "" {
+		t.Errorf("Expected password hash to be left out")
+	}
+	if len(archive.APIKeys) != 1 || archive.APIKeys[0].KeyHash != "" {
+		t.Errorf("Expected API key without its hash, got %+v", archive.APIKeys)
+	}
+	if len(archive.AuditEvents) == 0 {
+		t.Errorf("Expected the user's audit events")
+	}
+
+	// Test case: jobs of other users are not found
+	if _, err := service.GetJob(ctx, "user-2", job.ID); err != ErrPrivacyJobNotFound {
+		t.Errorf("Expected error %v, got %v", ErrPrivacyJobNotFound, err)
+	}
+
+	// Test case: unknown user
+	if _, err := service.RequestExport(ctx, "unknown"); err != ErrUserNotFound {
+		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
+	}
+}
+
+// Test an erasure job deletes the user's data and anonymizes their audit trail
+func TestPrivacyErasure(t *testing.T) {
+	service, jobs, stores, user := newPrivacyTestService(t)
+	ctx := model.WithPrincipal(context.Background(), model.Principal{UserID: user.ID, Role: model.RoleUser})
+
+	if _, err := service.RequestExport(ctx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	job, err := service.RequestErasure(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: both jobs run and the user is gone
+	if count, err := service.RunPendingJobs(context.Background()); err != nil || count != 2 {
+		t.Fatalf("Expected 2 jobs to run, got %d, %v", count, err)
+	}
+	done, err := service.GetJob(ctx, user.ID, job.ID)
+	if err != nil || done.Status != model.PrivacyJobCompleted {
+		t.Fatalf("Expected completed erasure, got %+v, %v", done, err)
+	}
+	if _, err := stores.Users.GetByID(context.Background(), user.ID); err == nil {
+		t.Errorf("Expected user to be erased")
+	}
+	if keys, _ := stores.APIKeys.ListByUser(context.Background(), user.ID); len(keys) != 0 {
+		t.Errorf("Expected API keys to be deleted, got %d", len(keys))
+	}
+
+	// Test case: the export archive is deleted with the user
+	for _, remaining := range jobs.jobs {
+		if remaining.Type == model.PrivacyJobExport {
+			t.Errorf("Expected export to be deleted, got %+v", remaining)
+		}
+	}
+
+	// Test case: the audit trail no longer names the user
+	events, _, _ := stores.Audit.List(context.Background(), &model.AuditFilter{})
+	if len(events) == 0 {
+		t.Fatalf("Expected audit events to be kept")
+	}
+	for _, event := range events {
+		if event.ActorID == user.ID || event.TargetID == user.ID || event.Details["email"] != "" {
+			t.Errorf("Expected event to be anonymized, got %+v", event)
+		}
+	}
+	if events[0].Action != model.AuditUserErased || !strings.HasPrefix(events[0].TargetID, "erased-") {
+		t.Errorf("Expected erasure to be recorded against the pseudonym, got %+v", events[0])
+	}
+}
+
+// Test an erasure that is retried after the account was removed still completes
+func TestPrivacyErasureRetry(t *testing.T) {
+	service, _, stores, user := newPrivacyTestService(t)
+	ctx := model.WithPrincipal(context.Background(), model.Principal{UserID: user.ID, Role: model.RoleUser})
+
+	job, err := service.RequestErasure(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: running the erasure again finds nothing left to do
+	for i := 0; i < 2; i++ {
+		if err := service.(*privacyService).erase(context.Background(), job); err != nil {
+			t.Fatalf("Expected no error on run %d, got %v", i+1, err)
+		}
+	}
+	if _, err := stores.Users.GetByID(context.Background(), user.ID); err == nil {
+		t.Errorf("Expected user to be erased")
+	}
+}
+
+// Test PurgeDeletedUsers deletes the data of users deleted before the cutoff
+func TestPurgeDeletedUsers(t *testing.T) {
+	service, _, stores, user := newPrivacyTestService(t)
+	ctx := context.Background()
+
+	if err := stores.Users.Delete(ctx, user.ID, time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: users deleted after the cutoff are kept
+	purged, err := service.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("Expected nothing purged, got %d, %v", purged, err)
+	}
+	if keys, _ := stores.APIKeys.ListByUser(ctx, user.ID); len(keys) != 1 {
+		t.Errorf("Expected API key to be kept, got %d", len(keys))
+	}
+
+	// Test case: the user is removed together with their data
+	purged, err = service.PurgeDeletedUsers(ctx, time.Now().Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Errorf("Expected 1 user purged, got %d, %v", purged, err)
+	}
+	if err := stores.Users.Restore(ctx, user.ID); err == nil {
+		t.Errorf("Expected purged user not to be restorable")
+	}
+	if keys, _ := stores.APIKeys.ListByUser(ctx, user.ID); len(keys) != 0 {
+		t.Errorf("Expected API keys to be deleted, got %d", len(keys))
+	}
+}
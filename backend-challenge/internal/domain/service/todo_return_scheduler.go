@@ -0,0 +1,147 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/repository"
+)
+
+// returnRetryDelay is how long the scheduler waits before retrying a return that failed
+const returnRetryDelay = 5 * time.Second
+
+// TodoReturnScheduler returns clicked todo items to the main list when they are due.
+// It is the only component that returns items.
+type TodoReturnScheduler interface {
+	// Schedule returns the item at returnAt. Scheduling an item again, for example
+	// after it was clicked again, does not require cancelling the earlier return.
+	Schedule(id string, returnAt time.Time)
+
+	// Run loads the items waiting in their type column and returns each of them
+	// when it is due, until the context is cancelled
+	Run(ctx context.Context) error
+}
+
+// scheduledReturn is an item waiting to be returned
+type scheduledReturn struct {
+	id       string
+	returnAt time.Time
+}
+
+// returnQueue is a min-heap of scheduled returns ordered by return time
+type returnQueue []scheduledReturn
+
+func (q returnQueue) Len() int            { return len(q) }
+func (q returnQueue) Less(i, j int) bool  { return q[i].returnAt.Before(q[j].returnAt) }
+func (q returnQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *returnQueue) Push(x interface{}) { *q = append(*q, x.(scheduledReturn)) }
+func (q *returnQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// todoReturnScheduler implements TodoReturnScheduler
+type todoReturnScheduler struct {
+	repo  repository.TodoRepository
+	mu    sync.Mutex
+	queue returnQueue
+	wake  chan struct{}
+}
+
+// NewTodoReturnScheduler creates a new TodoReturnScheduler
+func NewTodoReturnScheduler(repo repository.TodoRepository) TodoReturnScheduler {
+	return &todoReturnScheduler{
+		repo: repo,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Schedule returns the item at returnAt
+func (s *todoReturnScheduler) Schedule(id string, returnAt time.Time) {
+	s.mu.Lock()
+	heap.Push(&s.queue, scheduledReturn{id: id, returnAt: returnAt})
+	s.mu.Unlock()
+
+	// Wake the loop in case the item is due before the one it waits for
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run loads the items waiting in their type column and returns each of them when it is due
+func (s *todoReturnScheduler) Run(ctx context.Context) error {
+	// Rebuild the schedule, since items clicked before a restart are only stored in the database
+	todos, err := s.repo.FindScheduledReturns(ctx)
+	if err != nil {
+		return err
+	}
+	for _, todo := range todos {
+		s.Schedule(todo.ID, todo.ReturnAt)
+	}
+
+	for {
+		// Sleep until the next item is due or a new item is scheduled
+		var timer *time.Timer
+		var due <-chan time.Time
+		if next, ok := s.next(); ok {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case <-s.wake:
+		case <-due:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		s.returnDue(ctx)
+	}
+}
+
+// next returns the earliest scheduled return time
+func (s *todoReturnScheduler) next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].returnAt, true
+}
+
+// returnDue returns the items that are due. The update only matches items that
+// are still in their column and due, so returns scheduled by an earlier click
+// are skipped.
+func (s *todoReturnScheduler) returnDue(ctx context.Context) {
+	now := time.Now()
+
+	s.mu.Lock()
+	var due []scheduledReturn
+	for len(s.queue) > 0 && !s.queue[0].returnAt.After(now) {
+		due = append(due, heap.Pop(&s.queue).(scheduledReturn))
+	}
+	s.mu.Unlock()
+
+	for _, item := range due {
+		if _, err := s.repo.ReturnIfDue(ctx, item.id, now); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to return todo item %s: %v", item.id, err)
+			s.Schedule(item.id, now.Add(returnRetryDelay))
+		}
+	}
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"backend-challenge/internal/domain/model"
@@ -10,49 +12,134 @@ import (
 
 // Todo service errors are now defined in errors.go
 
-// TodoService defines the todo business logic service
+// TodoService defines the todo business logic service. Users only see and change
+// the items they own; items of other users are reported as not found.
 type TodoService interface {
-	// Create creates a new todo item
-	Create(ctx context.Context, input *model.CreateTodoInput) (*model.TodoItem, error)
+	// Create creates a new todo item owned by a user
+	Create(ctx context.Context, ownerID string, input *model.CreateTodoInput) (*model.TodoItem, error)
 
-	// GetByID fetches a todo item by ID
-	GetByID(ctx context.Context, id string) (*model.TodoItem, error)
+	// GetByID fetches a todo item of a user by ID
+	GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error)
 
-	// Update updates a todo item
-	Update(ctx context.Context, id string, input *model.UpdateTodoInput) (*model.TodoItem, error)
+	// Update updates a todo item of a user
+	Update(ctx context.Context, ownerID, id string, input *model.UpdateTodoInput) (*model.TodoItem, error)
 
-	// Delete removes a todo item
-	Delete(ctx context.Context, id string) error
+	// Delete removes a todo item of a user
+	Delete(ctx context.Context, ownerID, id string) error
 
-	// List returns all todo items grouped by status and type
-	List(ctx context.Context) (*model.TodosGrouped, error)
+	// List returns all todo items of a user grouped by status and type
+	List(ctx context.Context, ownerID string) (*model.TodosGrouped, error)
 	
-	// Click handles the click action on a todo item
-	Click(ctx context.Context, id string) (*model.TodoItem, error)
-	
-	// TimeoutReturn handles the automatic return of a todo item to the main list
-	TimeoutReturn(ctx context.Context, id string) error
-	
-	// ReturnTimedOutItems returns all todo items that should be returned to the main list
-	ReturnTimedOutItems(ctx context.Context, currentTime string) (int, error)
+	// Click handles the click action on a todo item of a user
+	Click(ctx context.Context, ownerID, id string) (*model.TodoItem, error)
+
+	// Return sends a todo item of a user from its type column straight back to the
+	// bottom of the main list, however long it had left in the column
+	Return(ctx context.Context, ownerID, id string) (*model.TodoItem, error)
+}
+
+// maxUpdateAttempts is how often an update is retried when the item changes concurrently
+const maxUpdateAttempts = 3
+
+// MaxReturnAfter is the longest an item may stay in its type column once clicked
+const MaxReturnAfter = 24 * time.Hour
+
+// ReturnDelays configures how long clicked items stay in their type column. The
+// delay of an item is, in order of precedence, its own ReturnAfter, the delay of
+// its type and the default.
+type ReturnDelays struct {
+	Default time.Duration
+	ByType  map[model.ItemType]time.Duration
+}
+
+// For returns the return delay of a todo item
+func (d ReturnDelays) For(todo *model.TodoItem) time.Duration {
+	if todo.ReturnAfter > 0 {
+		return time.Duration(todo.ReturnAfter)
+	}
+	if delay, ok := d.ByType[todo.Type]; ok && delay > 0 {
+		return delay
+	}
+	if d.Default > 0 {
+		return d.Default
+	}
+	return model.DefaultReturnAfter
+}
+
+// Validate checks the configured delays. Every delay must be positive and at most
+// MaxReturnAfter, and delays can only be set for known item types.
+func (d ReturnDelays) Validate() error {
+	if d.Default <= 0 || d.Default > MaxReturnAfter {
+		return fmt.Errorf("%w: default delay %v", ErrInvalidReturnAfter, d.Default)
+	}
+	for itemType, delay := range d.ByType {
+		if !itemType.IsValid() {
+			return fmt.Errorf("unknown item type %q", itemType)
+		}
+		if delay <= 0 || delay > MaxReturnAfter {
+			return fmt.Errorf("%w: delay %v for %s", ErrInvalidReturnAfter, delay, itemType)
+		}
+	}
+	return nil
 }
 
 // todoService implements TodoService
 type todoService struct {
-	repo repository.TodoRepository
+	repo      repository.TodoRepository
+	delays    ReturnDelays
+	scheduler TodoReturnScheduler
+}
+
+// TodoServiceOption configures optional features of the todo service
+type TodoServiceOption func(*todoService)
+
+// WithReturnDelays sets how long clicked items stay in their type column
+func WithReturnDelays(delays ReturnDelays) TodoServiceOption {
+	return func(s *todoService) {
+		s.delays = delays
+	}
+}
+
+// WithReturnScheduler sets the scheduler that returns clicked items to the main list
+func WithReturnScheduler(scheduler TodoReturnScheduler) TodoServiceOption {
+	return func(s *todoService) {
+		s.scheduler = scheduler
+	}
 }
 
 // NewTodoService creates a new TodoService
-func NewTodoService(repo repository.TodoRepository) TodoService {
-	return &todoService{
+func NewTodoService(repo repository.TodoRepository, opts ...TodoServiceOption) TodoService {
+	s := &todoService{
 		repo: repo,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// validateReturnAfter checks the return delay of an item, where zero means none
+func validateReturnAfter(returnAfter *model.Duration) error {
+	if returnAfter != nil && (*returnAfter < 0 || time.Duration(*returnAfter) > MaxReturnAfter) {
+		return ErrInvalidReturnAfter
+	}
+	return nil
 }
 
-// Create creates a new todo item
-func (s *todoService) Create(ctx context.Context, input *model.CreateTodoInput) (*model.TodoItem, error) {
+// Create creates a new todo item owned by a user
+func (s *todoService) Create(ctx context.Context, ownerID string, input *model.CreateTodoInput) (*model.TodoItem, error) {
+	if ownerID == "" {
+		return nil, ErrInvalidID
+	}
+	if !input.Type.IsValid() {
+		return nil, ErrInvalidTodoType
+	}
+	if err := validateReturnAfter(input.ReturnAfter); err != nil {
+		return nil, err
+	}
+
 	// Create new todo item
-	todo := model.NewTodoItem(input)
+	todo := model.NewTodoItem(ownerID, input)
 
 	// Save to repository
 	if err := s.repo.Create(ctx, todo); err != nil {
@@ -62,13 +149,13 @@ func (s *todoService) Create(ctx context.Context, input *model.CreateTodoInput)
 	return todo, nil
 }
 
-// GetByID fetches a todo item by ID
-func (s *todoService) GetByID(ctx context.Context, id string) (*model.TodoItem, error) {
+// GetByID fetches a todo item of a user by ID
+func (s *todoService) GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
 	if id == "" {
 		return nil, ErrInvalidID
 	}
 
-	todo, err := s.repo.GetByID(ctx, id)
+	todo, err := s.repo.GetByID(ctx, ownerID, id)
 	if err != nil {
 		return nil, ErrTodoNotFound
 	}
@@ -76,44 +163,54 @@ func (s *todoService) GetByID(ctx context.Context, id string) (*model.TodoItem,
 	return todo, nil
 }
 
-// Update updates a todo item
-func (s *todoService) Update(ctx context.Context, id string, input *model.UpdateTodoInput) (*model.TodoItem, error) {
-	// Get todo item
-	todo, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, ErrTodoNotFound
+// Update updates a todo item of a user. An item waiting in its type column is
+// rescheduled when its return delay changes, counted from when it was clicked.
+func (s *todoService) Update(ctx context.Context, ownerID, id string, input *model.UpdateTodoInput) (*model.TodoItem, error) {
+	if input.Type != "" && !input.Type.IsValid() {
+		return nil, ErrInvalidTodoType
+	}
+	if err := validateReturnAfter(input.ReturnAfter); err != nil {
+		return nil, err
 	}
 
-	// Update todo item
-	todo.Update(input)
-
-	// Save to repository
-	if err := s.repo.Update(ctx, todo); err != nil {
+	todo, err := s.modify(ctx, ownerID, id, func(todo *model.TodoItem) error {
+		todo.Update(input)
+		if todo.Status == model.StatusColumn {
+			todo.ReturnAt = todo.ClickedAt.Add(s.delays.For(todo))
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if todo.Status == model.StatusColumn {
+		s.schedule(todo)
+	}
+
 	return todo, nil
 }
 
-// Delete removes a todo item
-func (s *todoService) Delete(ctx context.Context, id string) error {
+// Delete removes a todo item of a user
+func (s *todoService) Delete(ctx context.Context, ownerID, id string) error {
 	if id == "" {
 		return ErrInvalidID
 	}
 
 	// Check if todo exists
-	_, err := s.repo.GetByID(ctx, id)
+	_, err := s.repo.GetByID(ctx, ownerID, id)
 	if err != nil {
 		return ErrTodoNotFound
 	}
 
-	return s.repo.Delete(ctx, id)
+	return s.repo.Delete(ctx, ownerID, id)
 }
 
-// List returns all todo items grouped by status and type
-func (s *todoService) List(ctx context.Context) (*model.TodosGrouped, error) {
-	// Get all todo items
-	todos, err := s.repo.List(ctx)
+// List returns all todo items of a user grouped by status and type. The main list
+// is ordered by when items entered it, so returned items are at the bottom.
+func (s *todoService) List(ctx context.Context, ownerID string) (*model.TodosGrouped, error) {
+	// Get all todo items of the user
+	todos, err := s.repo.List(ctx, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -135,80 +232,70 @@ func (s *todoService) List(ctx context.Context) (*model.TodosGrouped, error) {
 		}
 	}
 
+	sort.SliceStable(result.Main, func(i, j int) bool {
+		return result.Main[i].InMainSince().Before(result.Main[j].InMainSince())
+	})
+
 	return result, nil
 }
 
-// Click handles the click action on a todo item
-func (s *todoService) Click(ctx context.Context, id string) (*model.TodoItem, error) {
-	// Get todo item
-	todo, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, ErrTodoNotFound
-	}
-
+// Click handles the click action on a todo item of a user
+func (s *todoService) Click(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
 	// Mark as clicked and update status
-	todo.Click()
-
-	// Save to repository
-	if err := s.repo.Update(ctx, todo); err != nil {
+	todo, err := s.modify(ctx, ownerID, id, func(todo *model.TodoItem) error {
+		todo.Click(s.delays.For(todo))
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Schedule auto-return
-	time.AfterFunc(5*time.Second, func() {
-		// Create a background context since the HTTP context will be gone
-		bgCtx := context.Background()
-		// Call TimeoutReturn
-		_ = s.TimeoutReturn(bgCtx, id)
-	})
+	// Schedule auto-return at the return time of the item
+	s.schedule(todo)
 
 	return todo, nil
 }
 
-// TimeoutReturn handles the automatic return of a todo item to the main list
-func (s *todoService) TimeoutReturn(ctx context.Context, id string) error {
-	// Get todo item
-	todo, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return ErrTodoNotFound
-	}
-
-	// Only return if still in COLUMN status
-	if todo.Status == model.StatusColumn {
-		// Return to main list
-		todo.Return()
-
-		// Save to repository
-		if err := s.repo.Update(ctx, todo); err != nil {
-			return err
+// Return sends a todo item of a user from its type column back to the main list
+func (s *todoService) Return(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
+	// Return to main list. The scheduled return finds the item in the main
+	// list and leaves it alone.
+	return s.modify(ctx, ownerID, id, func(todo *model.TodoItem) error {
+		if todo.Status != model.StatusColumn {
+			return ErrTodoAlreadyInMain
 		}
-	}
-
-	return nil
+		todo.Return()
+		return nil
+	})
 }
 
-// ReturnTimedOutItems returns all todo items that should be returned to the main list
-func (s *todoService) ReturnTimedOutItems(ctx context.Context, currentTime string) (int, error) {
-	// Find items that need to be returned
-	items, err := s.repo.FindToReturn(ctx, currentTime)
-	if err != nil {
-		return 0, err
-	}
-
-	if len(items) == 0 {
-		return 0, nil
-	}
+// modify loads a todo item of a user, applies change and saves it. The save only
+// succeeds if the item was not changed in the meantime, for example returned by
+// the scheduler, otherwise the item is loaded again and the change reapplied.
+func (s *todoService) modify(ctx context.Context, ownerID, id string, change func(*model.TodoItem) error) (*model.TodoItem, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		todo, err := s.repo.GetByID(ctx, ownerID, id)
+		if err != nil {
+			return nil, ErrTodoNotFound
+		}
+		if err := change(todo); err != nil {
+			return nil, err
+		}
 
-	// Return each item
-	returnedCount := 0
-	for _, item := range items {
-		item.Return()
-		if err := s.repo.Update(ctx, item); err != nil {
-			// Log error but continue with other items
-			continue
+		saved, err := s.repo.Update(ctx, todo)
+		if err != nil {
+			return nil, err
+		}
+		if saved {
+			return todo, nil
 		}
-		returnedCount++
 	}
+	return nil, ErrTodoConflict
+}
 
-	return returnedCount, nil
-}
\ No newline at end of file
+// schedule asks the scheduler to return a clicked item at its return time
+func (s *todoService) schedule(todo *model.TodoItem) {
+	if s.scheduler != nil {
+		s.scheduler.Schedule(todo.ID, todo.ReturnAt)
+	}
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock TodoRepository for testing
+type mockTodoRepository struct {
+	mu    sync.Mutex
+	todos map[string]*model.TodoItem
+}
+
+func newMockTodoRepository() *mockTodoRepository {
+	return &mockTodoRepository{todos: make(map[string]*model.TodoItem)}
+}
+
+func (m *mockTodoRepository) Create(ctx context.Context, todo *model.TodoItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *todo
+	m.todos[todo.ID] = &copied
+	return nil
+}
+
+func (m *mockTodoRepository) GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todo, ok := m.todos[id]
+	if !ok || todo.OwnerID != ownerID {
+		return nil, errors.New("todo not found")
+	}
+	copied := *todo
+	return &copied, nil
+}
+
+func (m *mockTodoRepository) Update(ctx context.Context, todo *model.TodoItem) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.todos[todo.ID]
+	if !ok || existing.OwnerID != todo.OwnerID || existing.Version != todo.Version {
+		return false, nil
+	}
+	todo.UpdatedAt = time.Now()
+	todo.Version++
+	copied := *todo
+	m.todos[todo.ID] = &copied
+	return true, nil
+}
+
+func (m *mockTodoRepository) Delete(ctx context.Context, ownerID, id string) error {
+	if _, err := m.GetByID(ctx, ownerID, id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.todos, id)
+	return nil
+}
+
+func (m *mockTodoRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, todo := range m.todos {
+		if todo.OwnerID == ownerID {
+			delete(m.todos, id)
+		}
+	}
+	return nil
+}
+
+func (m *mockTodoRepository) List(ctx context.Context, ownerID string) ([]*model.TodoItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var todos []*model.TodoItem
+	for _, todo := range m.todos {
+		if todo.OwnerID == ownerID {
+			copied := *todo
+			todos = append(todos, &copied)
+		}
+	}
+	return todos, nil
+}
+
+func (m *mockTodoRepository) FindByStatus(ctx context.Context, ownerID string, status model.ItemStatus) ([]*model.TodoItem, error) {
+	return nil, nil
+}
+
+func (m *mockTodoRepository) FindByTypeAndStatus(ctx context.Context, ownerID string, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error) {
+	return nil, nil
+}
+
+func (m *mockTodoRepository) UpdateStatus(ctx context.Context, ownerID, id string, status model.ItemStatus) error {
+	return nil
+}
+
+func (m *mockTodoRepository) FindScheduledReturns(ctx context.Context) ([]*model.TodoItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var todos []*model.TodoItem
+	for _, todo := range m.todos {
+		if todo.Status == model.StatusColumn {
+			copied := *todo
+			todos = append(todos, &copied)
+		}
+	}
+	return todos, nil
+}
+
+func (m *mockTodoRepository) ReturnIfDue(ctx context.Context, id string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todo, ok := m.todos[id]
+	if !ok || todo.Status != model.StatusColumn || todo.ReturnAt.After(now) {
+		return false, nil
+	}
+	todo.Status = model.StatusMain
+	todo.ReturnedAt = now
+	todo.UpdatedAt = now
+	todo.Version++
+	return true, nil
+}
+
+// Test todo items are only visible to their owner
+func TestTodoOwnership(t *testing.T) {
+	repo := newMockTodoRepository()
+	service := NewTodoService(repo)
+	ctx := context.Background()
+
+	// Test case: an owner is required
+	if _, err := service.Create(ctx, "", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple"}); err != ErrInvalidID {
+		t.Errorf("Expected error %v, got %v", ErrInvalidID, err)
+	}
+
+	// Test case: unknown types are rejected
+	if _, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: "Mineral", Name: "Salt"}); err != ErrInvalidTodoType {
+		t.Errorf("Expected error %v, got %v", ErrInvalidTodoType, err)
+	}
+
+	todo, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if todo.OwnerID != "user-1" {
+		t.Errorf("Expected owner user-1, got %s", todo.OwnerID)
+	}
+	if _, err := service.Create(ctx, "user-2", &model.CreateTodoInput{Type: model.TypeVegetable, Name: "Carrot"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: the owner sees only their items
+	grouped, err := service.List(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(grouped.Main) != 1 || grouped.Main[0].ID != todo.ID {
+		t.Errorf("Expected only the owner's item, got %+v", grouped.Main)
+	}
+
+	// Test case: items of other users are not found
+	if _, err := service.GetByID(ctx, "user-2", todo.ID); err != ErrTodoNotFound {
+		t.Errorf("Expected error %v, got %v", ErrTodoNotFound, err)
+	}
+	if _, err := service.Update(ctx, "user-2", todo.ID, &model.UpdateTodoInput{Name: "Banana"}); err != ErrTodoNotFound {
+		t.Errorf("Expected error %v, got %v", ErrTodoNotFound, err)
+	}
+	if err := service.Delete(ctx, "user-2", todo.ID); err != ErrTodoNotFound {
+		t.Errorf("Expected error %v, got %v", ErrTodoNotFound, err)
+	}
+	if _, err := service.GetByID(ctx, "user-1", todo.ID); err != nil {
+		t.Errorf("Expected item to be kept, got %v", err)
+	}
+}
+
+// Test the return delay of an item comes from the item, its type or the default
+func TestReturnDelays(t *testing.T) {
+	delays := ReturnDelays{
+		Default: 10 * time.Second,
+		ByType:  map[model.ItemType]time.Duration{model.TypeFruit: time.Minute},
+	}
+
+	tests := []struct {
+		name string
+		todo *model.TodoItem
+		want time.Duration
+	}{
+		{"item override", &model.TodoItem{Type: model.TypeFruit, ReturnAfter: model.Duration(2 * time.Second)}, 2 * time.Second},
+		{"type delay", &model.TodoItem{Type: model.TypeFruit}, time.Minute},
+		{"default delay", &model.TodoItem{Type: model.TypeVegetable}, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := delays.For(tt.todo); got != tt.want {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// Test case: without configuration the model default applies
+	if got := (ReturnDelays{}).For(&model.TodoItem{Type: model.TypeFruit}); got != model.DefaultReturnAfter {
+		t.Errorf("Expected %v, got %v", model.DefaultReturnAfter, got)
+	}
+}
+
+// Test configured return delays are checked
+func TestReturnDelaysValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		delays  ReturnDelays
+		wantErr bool
+	}{
+		{"valid", ReturnDelays{Default: 5 * time.Second, ByType: map[model.ItemType]time.Duration{model.TypeFruit: time.Minute}}, false},
+		{"zero default", ReturnDelays{}, true},
+		{"default too long", ReturnDelays{Default: 25 * time.Hour}, true},
+		{"unknown type", ReturnDelays{Default: 5 * time.Second, ByType: map[model.ItemType]time.Duration{"Fruits": time.Minute}}, true},
+		{"zero type delay", ReturnDelays{Default: 5 * time.Second, ByType: map[model.ItemType]time.Duration{model.TypeVegetable: 0}}, true},
+		{"negative type delay", ReturnDelays{Default: 5 * time.Second, ByType: map[model.ItemType]time.Duration{model.TypeVegetable: -time.Second}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.delays.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// Test clicking sets the return time from the configured delay
+func TestClickReturnAfter(t *testing.T) {
+	repo := newMockTodoRepository()
+	service := NewTodoService(repo, WithReturnDelays(ReturnDelays{
+		ByType: map[model.ItemType]time.Duration{model.TypeVegetable: time.Hour},
+	}))
+	ctx := context.Background()
+
+	// Test case: invalid return delays
+	for _, returnAfter := range []model.Duration{model.Duration(-time.Second), model.Duration(MaxReturnAfter + time.Second)} {
+		returnAfter := returnAfter
+		if _, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple", ReturnAfter: &returnAfter}); err != ErrInvalidReturnAfter {
+			t.Errorf("Expected error %v, got %v", ErrInvalidReturnAfter, err)
+		}
+	}
+
+	// Test case: the type delay sets the return time
+	todo, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeVegetable, Name: "Carrot"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	clicked, err := service.Click(ctx, "user-1", todo.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := clicked.ReturnAt.Sub(clicked.ClickedAt); got != time.Hour {
+		t.Errorf("Expected return after %v, got %v", time.Hour, got)
+	}
+
+	// Test case: the item override takes precedence
+	returnAfter := model.Duration(30 * time.Second)
+	if _, err := service.Update(ctx, "user-1", todo.ID, &model.UpdateTodoInput{ReturnAfter: &returnAfter}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	clicked, err = service.Click(ctx, "user-1", todo.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := clicked.ReturnAt.Sub(clicked.ClickedAt); got != 30*time.Second {
+		t.Errorf("Expected return after %v, got %v", 30*time.Second, got)
+	}
+
+	// Test case: changing the delay of a clicked item moves its return time
+	returnAfter = model.Duration(2 * time.Minute)
+	updated, err := service.Update(ctx, "user-1", todo.ID, &model.UpdateTodoInput{ReturnAfter: &returnAfter})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := updated.ReturnAt.Sub(clicked.ClickedAt); got != 2*time.Minute {
+		t.Errorf("Expected return after %v, got %v", 2*time.Minute, got)
+	}
+}
+
+// Test the scheduler returns items when they are due, including items clicked before it started
+func TestTodoReturnScheduler(t *testing.T) {
+	repo := newMockTodoRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// An item clicked before a restart is only stored in the repository
+	stored := model.NewTodoItem("user-1", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple"})
+	stored.Click(-time.Second)
+	repo.Create(ctx, stored)
+
+	scheduler := NewTodoReturnScheduler(repo)
+	service := NewTodoService(repo, WithReturnScheduler(scheduler), WithReturnDelays(ReturnDelays{Default: 50 * time.Millisecond}))
+	go scheduler.Run(ctx)
+
+	// waitForStatus polls the item until it has the status or the time is up
+	waitForStatus := func(id string, status model.ItemStatus, within time.Duration) bool {
+		deadline := time.Now().Add(within)
+		for time.Now().Before(deadline) {
+			if todo, err := service.GetByID(ctx, "user-1", id); err == nil && todo.Status == status {
+				return true
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return false
+	}
+
+	// Test case: the stored item is returned once the schedule is rebuilt
+	if !waitForStatus(stored.ID, model.StatusMain, time.Second) {
+		t.Errorf("Expected stored item to be returned")
+	}
+
+	// Test case: a clicked item is returned at its return time
+	todo, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeVegetable, Name: "Carrot"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := service.Click(ctx, "user-1", todo.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !waitForStatus(todo.ID, model.StatusMain, time.Second) {
+		t.Errorf("Expected clicked item to be returned")
+	}
+
+	// Test case: clicking again with a longer delay is not undone by the earlier return
+	if _, err := service.Click(ctx, "user-1", todo.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	returnAfter := model.Duration(time.Hour)
+	if _, err := service.Update(ctx, "user-1", todo.ID, &model.UpdateTodoInput{ReturnAfter: &returnAfter}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := service.Click(ctx, "user-1", todo.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if waitForStatus(todo.ID, model.StatusMain, 200*time.Millisecond) {
+		t.Errorf("Expected item to stay in its column until its new return time")
+	}
+}
+
+// Test Return sends an item from its column to the bottom of the main list
+func TestTodoReturn(t *testing.T) {
+	repo := newMockTodoRepository()
+	service := NewTodoService(repo, WithReturnDelays(ReturnDelays{Default: time.Hour}))
+	ctx := context.Background()
+
+	apple, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	carrot, err := service.Create(ctx, "user-1", &model.CreateTodoInput{Type: model.TypeVegetable, Name: "Carrot"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: items in the main list cannot be returned
+	if _, err := service.Return(ctx, "user-1", apple.ID); err != ErrTodoAlreadyInMain {
+		t.Errorf("Expected error %v, got %v", ErrTodoAlreadyInMain, err)
+	}
+
+	// Test case: a clicked item returns at once, whatever time it has left
+	if _, err := service.Click(ctx, "user-1", apple.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	returned, err := service.Return(ctx, "user-1", apple.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if returned.Status != model.StatusMain {
+		t.Errorf("Expected status %s, got %s", model.StatusMain, returned.Status)
+	}
+
+	// Test case: the returned item is at the bottom of the main list
+	grouped, err := service.List(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(grouped.Main) != 2 || grouped.Main[0].ID != carrot.ID || grouped.Main[1].ID != apple.ID {
+		t.Errorf("Expected returned item at the bottom of the main list, got %+v", grouped.Main)
+	}
+
+	// Test case: items of other users are not found
+	if _, err := service.Return(ctx, "user-2", apple.ID); err != ErrTodoNotFound {
+		t.Errorf("Expected error %v, got %v", ErrTodoNotFound, err)
+	}
+}
+
+// racingTodoRepository returns the item when its first update is saved, as if the
+// scheduler returned it between the read and the write of the update
+type racingTodoRepository struct {
+	*mockTodoRepository
+	raced bool
+}
+
+func (r *racingTodoRepository) Update(ctx context.Context, todo *model.TodoItem) (bool, error) {
+	if !r.raced {
+		r.raced = true
+		r.ReturnIfDue(ctx, todo.ID, time.Now())
+	}
+	return r.mockTodoRepository.Update(ctx, todo)
+}
+
+// Test an update does not undo a return that happened while it was in progress
+func TestTodoUpdateDuringReturn(t *testing.T) {
+	repo := &racingTodoRepository{mockTodoRepository: newMockTodoRepository()}
+	service := NewTodoService(repo)
+	ctx := context.Background()
+
+	// An item that is due to return
+	todo := model.NewTodoItem("user-1", &model.CreateTodoInput{Type: model.TypeFruit, Name: "Apple"})
+	todo.Click(-time.Second)
+	repo.Create(ctx, todo)
+
+	// Test case: the update is applied to the returned item
+	updated, err := service.Update(ctx, "user-1", todo.ID, &model.UpdateTodoInput{Name: "Banana"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.Status != model.StatusMain {
+		t.Errorf("Expected status %s, got %s", model.StatusMain, updated.Status)
+	}
+	if updated.Name != "Banana" {
+		t.Errorf("Expected name Banana, got %s", updated.Name)
+	}
+	if updated.ReturnedAt.IsZero() {
+		t.Errorf("Expected the return time to be kept")
+	}
+}
@@ -0,0 +1,9 @@
+package service
+
+import "context"
+
+// TokenRevoker signs users out of every session
+type TokenRevoker interface {
+	// RevokeAllForUser revokes every access and refresh token and every session of a user
+	RevokeAllForUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/pkg/cursor"
+	"backend-challenge/pkg/securetoken"
+	"backend-challenge/pkg/validator"
+)
+
+const (
+	// MaxImportRows is the number of users one bulk import can create
+	MaxImportRows = 1000
+
+	// exportPageSize is the number of users read from the repository at a time during an export
+	exportPageSize = 100
+)
+
+// ImportUsers validates rows and creates the users of the valid ones in one batch,
+// reporting the problems with every other row. A dry run only validates.
+func (s *userService) ImportUsers(ctx context.Context, rows []*model.ImportUserRow, dryRun bool) (*model.ImportResult, error) {
+	if len(rows) > MaxImportRows {
+		return nil, ErrTooManyImportRows
+	}
+
+	result := &model.ImportResult{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Errors: []model.ImportRowError{},
+	}
+
+	// Validate every row before creating any user
+	seen := make(map[string]bool)
+	var valid []*model.ImportUserRow
+	for _, row := range rows {
+		if errs := s.validateImportRow(ctx, row, seen); len(errs) > 0 {
+			result.Errors = append(result.Errors, model.ImportRowError{Row: row.Row, Email: row.Email, Errors: errs})
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	if dryRun || len(valid) == 0 {
+		result.Created = len(valid)
+		result.Failed = len(result.Errors)
+		return result, nil
+	}
+
+	// Hash passwords. Users without one get a random password and choose their own
+	// with a password reset.
+	users := make([]*model.User, len(valid))
+	for i, row := range valid {
+		password := row.Password
+		if password == "" {
+			random, err := securetoken.New(0)
+			if err != nil {
+				return nil, err
+			}
+			password = random
+		}
+
+		user, err := model.NewUser(&model.RegisterUserInput{Name: row.Name, Email: row.Email, Password: password}, s.hasher)
+		if err != nil {
+			return nil, err
+		}
+		if row.Role != "" {
+			user.Role = row.Role
+		}
+		users[i] = user
+	}
+
+	// Emails can be taken by users created since validation or by deleted users
+	// that have not been purged yet
+	duplicates, err := s.repo.CreateMany(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	skipped := make(map[int]bool, len(duplicates))
+	for _, i := range duplicates {
+		skipped[i] = true
+		result.Errors = append(result.Errors, model.ImportRowError{
+			Row:    valid[i].Row,
+			Email:  valid[i].Email,
+			Errors: map[string]string{"email": ErrEmailExists.Error()},
+		})
+	}
+	sort.Slice(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+
+	for i, user := range users {
+		if skipped[i] {
+			continue
+		}
+		result.Created++
+		s.record(ctx, model.AuditUserImported, user.ID, nil)
+	}
+	result.Failed = len(result.Errors)
+
+	return result, nil
+}
+
+// validateImportRow returns the problems with a row keyed by field. Emails already
+// seen in the import are rejected, and the row's email is added to them.
+func (s *userService) validateImportRow(ctx context.Context, row *model.ImportUserRow, seen map[string]bool) map[string]string {
+	v := validator.New()
+	_ = v.ValidateStruct(row)
+
+	if row.Role != "" && !row.Role.IsValid() {
+		v.AddError("role", ErrInvalidRole.Error())
+	}
+
+	if _, invalid := v.Errors["email"]; !invalid {
+		if seen[row.Email] {
+			v.AddError("email", ErrDuplicateImportEmail.Error())
+		} else if existing, _ := s.repo.GetByEmail(ctx, row.Email); existing != nil {
+			v.AddError("email", ErrEmailExists.Error())
+		}
+		seen[row.Email] = true
+	}
+
+	if row.Password != "" {
+		if err := s.passwordPolicy.Check(ctx, row.Password, row.Email, row.Name); err != nil {
+			v.AddError("password", err.Error())
+		}
+	}
+
+	return v.GetErrorMessages()
+}
+
+// ExportUsers calls fn for every user, oldest first, reading users a page at a time
+// so that large exports can be streamed. It stops at the first error returned by fn.
+func (s *userService) ExportUsers(ctx context.Context, fn func(*model.User) error) error {
+	opts := &model.UserListOptions{
+		SortBy:   model.UserSortCreatedAt,
+		Order:    model.SortAsc,
+		Page:     1,
+		PageSize: exportPageSize,
+	}
+
+	for {
+		users, _, err := s.repo.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+
+		if len(users) < exportPageSize {
+			return nil
+		}
+
+		// Continue after the last user, which stays correct while users are created
+		last := users[len(users)-1]
+		after := cursor.New(last.CreatedAt, last.ID)
+		opts.After = &after
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Test ImportUsers creates valid rows and reports invalid ones
+func TestImportUsers(t *testing.T) {
+	// Create mock repository with an existing user
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	if _, err := service.Register(context.Background(), &model.RegisterUserInput{
+		Name:     "Existing User",
+		Email:    "existing@example.com",
+		Password: "password123",
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	rows := []*model.ImportUserRow{
+		{Row: 1, Name: "Alice Smith", Email: "alice@example.com", Password: "password123"},
+		{Row: 2, Name: "Bob Jones", Email: "bob@example.com", Role: model.RoleAdmin},
+		{Row: 3, Name: "B", Email: "not-an-email"},
+		{Row: 4, Name: "Alice Again", Email: "alice@example.com"},
+		{Row: 5, Name: "Existing User", Email: "existing@example.com"},
+		{Row: 6, Name: "Carol White", Email: "carol@example.com", Password: "short"},
+		{Row: 7, Name: "Dave Brown", Email: "dave@example.com", Role: "superuser"},
+	}
+	wantErrors := map[int]string{3: "name", 4: "email", 5: "email", 6: "password", 7: "role"}
+
+	// Test case: dry run validates without creating users
+	result, err := service.ImportUsers(context.Background(), rows, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.DryRun || result.Total != 7 || result.Created != 2 || result.Failed != 5 {
+		t.Errorf("Unexpected dry run result %+v", result)
+	}
+	if len(repo.users) != 1 {
+		t.Errorf("Expected dry run not to create users, got %d users", len(repo.users))
+	}
+	for _, rowErr := range result.Errors {
+		field, ok := wantErrors[rowErr.Row]
+		if !ok {
+			t.Errorf("Unexpected error for row %d: %v", rowErr.Row, rowErr.Errors)
+		} else if _, ok := rowErr.Errors[field]; !ok {
+			t.Errorf("Expected %s error for row %d, got %v", field, rowErr.Row, rowErr.Errors)
+		}
+	}
+
+	// Test case: import creates the valid rows
+	result, err = service.ImportUsers(context.Background(), rows, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.DryRun || result.Created != 2 || result.Failed != 5 {
+		t.Errorf("Unexpected import result %+v", result)
+	}
+	alice, err := repo.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("Expected imported user, got %v", err)
+	}
+	if !alice.CheckPassword("password123") {
+		t.Errorf("Expected imported password to be hashed")
+	}
+	bob, err := repo.GetByEmail(context.Background(), "bob@example.com")
+	if err != nil {
+		t.Fatalf("Expected imported user, got %v", err)
+	}
+	if !bob.IsAdmin() || bob.Password == "" {
+		t.Errorf("Expected admin with a random password, got %+v", bob)
+	}
+
+	// Test case: too many rows
+	tooMany := make([]*model.ImportUserRow, MaxImportRows+1)
+	if _, err := service.ImportUsers(context.Background(), tooMany, true); err != ErrTooManyImportRows {
+		t.Errorf("Expected error %v, got %v", ErrTooManyImportRows, err)
+	}
+}
+
+// Test ExportUsers visits every user oldest first
+func TestExportUsers(t *testing.T) {
+	// Create mock repository
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if _, err := service.Register(context.Background(), &model.RegisterUserInput{
+			Name:     "Test User",
+			Email:    email,
+			Password: "password123",
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// Test case: every user is exported once, oldest first
+	var emails []string
+	err := service.ExportUsers(context.Background(), func(user *model.User) error {
+		emails = append(emails, user.Email)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(emails) != 3 || emails[0] != "a@example.com" || emails[2] != "c@example.com" {
+		t.Errorf("Expected users oldest first, got %v", emails)
+	}
+
+	// Test case: the export stops at the first error
+	stop := errors.New("stop")
+	calls := 0
+	err = service.ExportUsers(context.Background(), func(user *model.User) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Expected export to stop after one user with %v, got %v after %d", stop, err, calls)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/pkg/securetoken"
+	"backend-challenge/pkg/totp"
+)
+
+const (
+	// defaultMFAIssuer is the issuer name shown in authenticator apps
+	defaultMFAIssuer = "backend-challenge"
+
+	// recoveryCodeCount is the number of recovery codes issued on enrollment
+	recoveryCodeCount = 10
+
+	// mfaSkew is the number of time steps of clock drift accepted in each direction
+	mfaSkew = 1
+)
+
+// EnrollMFA generates a new TOTP secret for a user, active once confirmed
+func (s *userService) EnrollMFA(ctx context.Context, userID string) (*model.MFAEnrollment, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replaces any earlier unconfirmed enrollment
+	user.MFA = model.MFA{Secret: secret}
+
+	// Save to repository
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	enrollment := &model.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.mfaIssuer, user.Email, secret),
+	}
+
+	return enrollment, nil
+}
+
+// ConfirmMFA enables two-factor authentication with a code from the enrolled secret
+// and returns single-use recovery codes
+func (s *userService) ConfirmMFA(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFA.Secret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := totp.Validate(user.MFA.Secret, code, time.Now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFA.Enabled = true
+	user.MFA.RecoveryCodes = hashes
+	user.MFA.LastStep = step
+
+	// Save to repository
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA turns off two-factor authentication after checking the password and a code.
+// Wrong passwords and codes count towards the login throttle of the account.
+func (s *userService) DisableMFA(ctx context.Context, userID string, input *model.MFADisableInput) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.MFA.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	ip := model.ClientInfoFromContext(ctx).IP
+	if s.throttle != nil {
+		if err := s.throttle.Reserve(ctx, user.Email, ip); err != nil {
+			return err
+		}
+	}
+
+	if !s.hasher.Verify(input.Password, user.Password) {
+		return ErrInvalidPassword
+	}
+	if _, ok := checkMFACode(user.MFA, input.Code); !ok {
+		return ErrInvalidMFACode
+	}
+
+	// Only applies if the code was not used by a concurrent request
+	updated, err := s.repo.UpdateMFA(ctx, user.ID, user.MFA, model.MFA{})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrInvalidMFACode
+	}
+	user.MFA = model.MFA{}
+
+	if s.throttle != nil {
+		if err := s.throttle.RecordSuccess(ctx, user.Email, ip); err != nil {
+			return err
+		}
+	}
+
+	s.record(ctx, model.AuditMFADisabled, user.ID, nil)
+
+	return nil
+}
+
+// VerifyMFA checks the second factor of a login with a TOTP or recovery code.
+// Failures count towards the login throttle of the account.
+func (s *userService) VerifyMFA(ctx context.Context, userID, code string) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.MFA.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	ip := model.ClientInfoFromContext(ctx).IP
+	if s.throttle != nil {
+		if err := s.throttle.Reserve(ctx, user.Email, ip); err != nil {
+			s.recordLoginFailure(ctx, user.ID, user.Email, "locked")
+			return nil, err
+		}
+	}
+
+	mfa, ok := checkMFACode(user.MFA, code)
+	if !ok {
+		s.recordLoginFailure(ctx, user.ID, user.Email, "invalid_mfa_code")
+		return nil, ErrInvalidMFACode
+	}
+
+	// Persist the accepted step or the consumed recovery code. A concurrent
+	// request that used the same code first leaves nothing to update.
+	updated, err := s.repo.UpdateMFA(ctx, user.ID, user.MFA, mfa)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		s.recordLoginFailure(ctx, user.ID, user.Email, "invalid_mfa_code")
+		return nil, ErrInvalidMFACode
+	}
+	user.MFA = mfa
+
+	if s.throttle != nil {
+		if err := s.throttle.RecordSuccess(ctx, user.Email, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	s.recordAs(ctx, user.ID, model.AuditLoginSucceeded, user.ID, map[string]string{"mfa": "true"})
+
+	return user, nil
+}
+
+// checkMFACode accepts a TOTP code that has not been used before or an unused
+// recovery code and returns the MFA state with the code used up
+func checkMFACode(mfa model.MFA, code string) (model.MFA, bool) {
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now(), mfaSkew); ok {
+		if step <= mfa.LastStep {
+			return mfa, false
+		}
+		mfa.LastStep = step
+		return mfa, true
+	}
+
+	hash := securetoken.Hash(normalizeRecoveryCode(code))
+	for i, stored := range mfa.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			// Recovery codes can only be used once
+			mfa.RecoveryCodes = append(mfa.RecoveryCodes[:i:i], mfa.RecoveryCodes[i+1:]...)
+			return mfa, true
+		}
+	}
+
+	return mfa, false
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		// 16 characters, shown in groups of four
+		raw := encoding.EncodeToString(buf)
+		codes[i] = strings.ToLower(raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16])
+		hashes[i] = securetoken.Hash(normalizeRecoveryCode(codes[i]))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode makes recovery codes insensitive to case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/pkg/totp"
+)
+
+// Test MFA
+func TestMFA(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service
+	service := NewUserService(newMockUserRepository(), WithMFAIssuer("Test"))
+	user, _ := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+
+	// Test case: confirm before enrollment
+	if _, err := service.ConfirmMFA(ctx, user.ID, "123456"); err != ErrMFANotEnrolled {
+		t.Errorf("Expected error %v, got %v", ErrMFANotEnrolled, err)
+	}
+
+	// Test case: enrollment returns secret and URI
+	enrollment, err := service.EnrollMFA(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if enrollment.Secret == "" || enrollment.URI != totp.URI("Test", user.Email, enrollment.Secret) {
+		t.Errorf("Unexpected enrollment %+v", enrollment)
+	}
+
+	// Test case: wrong code does not enable MFA
+	if _, err := service.ConfirmMFA(ctx, user.ID, "000000x"); err != ErrInvalidMFACode {
+		t.Errorf("Expected error %v, got %v", ErrInvalidMFACode, err)
+	}
+
+	// Test case: successful confirmation
+	previousCode, _ := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	recoveryCodes, err := service.ConfirmMFA(ctx, user.ID, previousCode)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+	if _, err := service.EnrollMFA(ctx, user.ID); err != ErrMFAAlreadyEnabled {
+		t.Errorf("Expected error %v, got %v", ErrMFAAlreadyEnabled, err)
+	}
+
+	// Test case: a used code cannot be replayed
+	if _, err := service.VerifyMFA(ctx, user.ID, previousCode); err != ErrInvalidMFACode {
+		t.Errorf("Expected error %v, got %v", ErrInvalidMFACode, err)
+	}
+
+	// Test case: current code is accepted
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	if _, err := service.VerifyMFA(ctx, user.ID, code); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// Test case: recovery codes work once, regardless of format
+	if _, err := service.VerifyMFA(ctx, user.ID, " "+recoveryCodes[0]+" "); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := service.VerifyMFA(ctx, user.ID, recoveryCodes[0]); err != ErrInvalidMFACode {
+		t.Errorf("Expected error %v, got %v", ErrInvalidMFACode, err)
+	}
+
+	// Test case: disable requires the password
+	disable := &model.MFADisableInput{Password: "wrongpassword", Code: recoveryCodes[1]}
+	if err := service.DisableMFA(ctx, user.ID, disable); err != ErrInvalidPassword {
+		t.Errorf("Expected error %v, got %v", ErrInvalidPassword, err)
+	}
+	disable.Password = "password123"
+	if err := service.DisableMFA(ctx, user.ID, disable); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := service.VerifyMFA(ctx, user.ID, code); err != ErrMFANotEnabled {
+		t.Errorf("Expected error %v, got %v", ErrMFANotEnabled, err)
+	}
+}
+
+// staleUserRepository returns a user as it was read before a concurrent request changed it
+type staleUserRepository struct {
+	*mockUserRepository
+	stale model.User
+}
+
+func (m *staleUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+	user := m.stale
+	return &user, nil
+}
+
+// Test VerifyMFA with a code used by a concurrent request
+func TestVerifyMFAConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service with MFA enabled for a user
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	user, _ := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	enrollment, err := service.EnrollMFA(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	previousCode, _ := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	recoveryCodes, err := service.ConfirmMFA(ctx, user.ID, previousCode)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Both requests read the user before either used the code
+	stale := &staleUserRepository{mockUserRepository: repo, stale: *repo.users[user.ID]}
+	staleService := NewUserService(stale)
+
+	// Test case: the code is accepted once
+	if _, err := service.VerifyMFA(ctx, user.ID, recoveryCodes[0]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := staleService.VerifyMFA(ctx, user.ID, recoveryCodes[0]); err != ErrInvalidMFACode {
+		t.Errorf("Expected error %v, got %v", ErrInvalidMFACode, err)
+	}
+
+	// Test case: disabling with a used code fails
+	disable := &model.MFADisableInput{Password: "password123", Code: recoveryCodes[0]}
+	if err := staleService.DisableMFA(ctx, user.ID, disable); err != ErrInvalidMFACode {
+		t.Errorf("Expected error %v, got %v", ErrInvalidMFACode, err)
+	}
+	if !repo.users[user.ID].MFA.Enabled {
+		t.Error("Expected MFA to stay enabled")
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import "backend-challenge/internal/domain/model"
+
+// Actor is the authenticated caller performing an operation, as stored in the
+// request context by the auth middleware
+type Actor = model.Principal
+
+// UserPolicy decides which user operations an actor is allowed to perform
+type UserPolicy interface {
+	// CanList reports whether the actor may list all users
+	CanList(actor Actor) bool
+
+	// CanImport reports whether the actor may create users in bulk
+	CanImport(actor Actor) bool
+
+	// CanExport reports whether the actor may export all users
+	CanExport(actor Actor) bool
+
+	// CanView reports whether the actor may view the target user
+	CanView(actor Actor, targetID string) bool
+
+	// CanUpdate reports whether the actor may update the target user
+	CanUpdate(actor Actor, targetID string) bool
+
+	// CanDelete reports whether the actor may delete the target user
+	CanDelete(actor Actor, targetID string) bool
+
+	// CanRestore reports whether the actor may restore the deleted target user
+	CanRestore(actor Actor, targetID string) bool
+
+	// CanChangeRole reports whether the actor may change the target user's role
+	CanChangeRole(actor Actor, targetID string) bool
+
+	// CanChangePassword reports whether the actor may change the target user's password
+	CanChangePassword(actor Actor, targetID string) bool
+
+	// CanExportData reports whether the actor may export the target user's personal data
+	CanExportData(actor Actor, targetID string) bool
+
+	// CanErase reports whether the actor may erase the target user and their data
+	CanErase(actor Actor, targetID string) bool
+}
+
+// roleBasedUserPolicy implements UserPolicy: admins manage everyone,
+// regular users only their own account
+type roleBasedUserPolicy struct{}
+
+// NewUserPolicy creates the default role based UserPolicy
+func NewUserPolicy() UserPolicy {
+	return roleBasedUserPolicy{}
+}
+
+// CanList reports whether the actor may list all users
+func (roleBasedUserPolicy) CanList(actor Actor) bool {
+	return actor.IsAdmin()
+}
+
+// CanImport reports whether the actor may create users in bulk
+func (roleBasedUserPolicy) CanImport(actor Actor) bool {
+	return actor.IsAdmin()
+}
+
+// CanExport reports whether the actor may export all users
+func (roleBasedUserPolicy) CanExport(actor Actor) bool {
+	return actor.IsAdmin()
+}
+
+// CanView reports whether the actor may view the target user
+func (roleBasedUserPolicy) CanView(actor Actor, targetID string) bool {
+	return actor.IsAdmin() || isSelf(actor, targetID)
+}
+
+// CanUpdate reports whether the actor may update the target user
+func (roleBasedUserPolicy) CanUpdate(actor Actor, targetID string) bool {
+	return actor.IsAdmin() || isSelf(actor, targetID)
+}
+
+// CanDelete reports whether the actor may delete the target user
+func (roleBasedUserPolicy) CanDelete(actor Actor, targetID string) bool {
+	return actor.IsAdmin() || isSelf(actor, targetID)
+}
+
+// CanRestore reports whether the actor may restore the deleted target user.
+// Deleted users cannot sign in, so only admins can restore accounts.
+func (roleBasedUserPolicy) CanRestore(actor Actor, targetID string) bool {
+	return actor.IsAdmin()
+}
+
+// CanChangeRole reports whether the actor may change the target user's role.
+// Admins cannot change their own role so that they cannot lock themselves out.
+func (roleBasedUserPolicy) CanChangeRole(actor Actor, targetID string) bool {
+	return actor.IsAdmin() && !isSelf(actor, targetID)
+}
+
+// CanChangePassword reports whether the actor may change the target user's password.
+// Changing a password requires the current one, so only the user can do it.
+func (roleBasedUserPolicy) CanChangePassword(actor Actor, targetID string) bool {
+	return isSelf(actor, targetID)
+}
+
+// CanExportData reports whether the actor may export the target user's personal data
+func (roleBasedUserPolicy) CanExportData(actor Actor, targetID string) bool {
+	return actor.IsAdmin() || isSelf(actor, targetID)
+}
+
+// CanErase reports whether the actor may erase the target user and their data
+func (roleBasedUserPolicy) CanErase(actor Actor, targetID string) bool {
+	return actor.IsAdmin() || isSelf(actor, targetID)
+}
+
+// isSelf reports whether the actor is the target user
+func isSelf(actor Actor, targetID string) bool {
+	return actor.UserID != "" && actor.UserID == targetID
+}
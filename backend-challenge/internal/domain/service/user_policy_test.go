@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Test UserPolicy
+func TestUserPolicy(t *testing.T) {
+	policy := NewUserPolicy()
+
+	admin := Actor{UserID: "admin-1", Role: model.RoleAdmin}
+	user := Actor{UserID: "user-1", Role: model.RoleUser}
+
+	// Admins manage everyone
+	if !policy.CanList(admin) {
+		t.Errorf("Expected admin to list users")
+	}
+	if !policy.CanImport(admin) || !policy.CanExport(admin) {
+		t.Errorf("Expected admin to import and export users")
+	}
+	if !policy.CanUpdate(admin, "user-1") || !policy.CanDelete(admin, "user-1") {
+		t.Errorf("Expected admin to manage other users")
+	}
+	if !policy.CanChangeRole(admin, "user-1") {
+		t.Errorf("Expected admin to change other users' roles")
+	}
+	if policy.CanChangeRole(admin, "admin-1") {
+		t.Errorf("Expected admin not to change their own role")
+	}
+	if !policy.CanRestore(admin, "user-1") {
+		t.Errorf("Expected admin to restore deleted users")
+	}
+
+	// Regular users only manage themselves
+	if policy.CanList(user) {
+		t.Errorf("Expected user not to list users")
+	}
+	if policy.CanImport(user) || policy.CanExport(user) {
+		t.Errorf("Expected user not to import or export users")
+	}
+	if !policy.CanView(user, "user-1") || !policy.CanUpdate(user, "user-1") || !policy.CanDelete(user, "user-1") {
+		t.Errorf("Expected user to manage their own account")
+	}
+	if policy.CanView(user, "user-2") || policy.CanUpdate(user, "user-2") || policy.CanDelete(user, "user-2") {
+		t.Errorf("Expected user not to manage other accounts")
+	}
+	if policy.CanChangeRole(user, "user-1") {
+		t.Errorf("Expected user not to change roles")
+	}
+	if policy.CanRestore(user, "user-1") {
+		t.Errorf("Expected user not to restore accounts")
+	}
+
+	// Personal data is exported and erased by the user or an admin
+	if !policy.CanExportData(user, "user-1") || !policy.CanErase(user, "user-1") {
+		t.Errorf("Expected user to export and erase their own data")
+	}
+	if policy.CanExportData(user, "user-2") || policy.CanErase(user, "user-2") {
+		t.Errorf("Expected user not to export or erase another user's data")
+	}
+	if !policy.CanExportData(admin, "user-1") || !policy.CanErase(admin, "user-1") {
+		t.Errorf("Expected admin to export and erase other users' data")
+	}
+
+	// Only users change their own password
+	if !policy.CanChangePassword(user, "user-1") {
+		t.Errorf("Expected user to change their own password")
+	}
+	if policy.CanChangePassword(user, "user-2") || policy.CanChangePassword(admin, "user-1") {
+		t.Errorf("Expected nobody to change another user's password")
+	}
+}
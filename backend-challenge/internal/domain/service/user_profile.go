@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/pkg/securetoken"
+	"backend-challenge/pkg/thumbnail"
+)
+
+const (
+	// MaxAvatarSize is the largest avatar upload accepted, in bytes
+	MaxAvatarSize = 2 << 20
+
+	// MaxAvatarDimension is the largest width or height of an avatar, which bounds
+	// the memory needed to decode it
+	MaxAvatarDimension = 4096
+
+	// AvatarThumbnailSize is the width and height of avatar thumbnails
+	AvatarThumbnailSize = 128
+
+	// Longest profile fields, in characters
+	maxDisplayNameLength = 100
+	maxBioLength         = 500
+)
+
+// avatarContentTypes maps the image formats accepted as avatars to their content types
+var avatarContentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+// localePattern matches BCP 47 language tags such as en, th-TH or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// AvatarImage is the image data of an avatar or its thumbnail
+type AvatarImage struct {
+	Data        []byte
+	ContentType string
+	UpdatedAt   time.Time
+}
+
+// validateProfile trims the profile fields of an update and checks them
+func validateProfile(input *model.UpdateUserInput) error {
+	for _, field := range []*string{input.DisplayName, input.Locale, input.Timezone, input.Bio} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	if input.DisplayName != nil && utf8.RuneCountInString(*input.DisplayName) > maxDisplayNameLength {
+		return ErrInvalidDisplayName
+	}
+	if input.Locale != nil && *input.Locale != "" && !localePattern.MatchString(*input.Locale) {
+		return ErrInvalidLocale
+	}
+	if input.Timezone != nil && *input.Timezone != "" {
+		// LoadLocation also accepts Local, the time zone of the server
+		if _, err := time.LoadLocation(*input.Timezone); err != nil || *input.Timezone == "Local" {
+			return ErrInvalidTimezone
+		}
+	}
+	if input.Bio != nil && utf8.RuneCountInString(*input.Bio) > maxBioLength {
+		return ErrInvalidBio
+	}
+
+	return nil
+}
+
+// SetAvatar replaces the avatar of a user with a JPEG, PNG or GIF image and
+// generates its thumbnail
+func (s *userService) SetAvatar(ctx context.Context, userID string, data []byte) (*model.User, error) {
+	if s.avatars == nil {
+		return nil, ErrAvatarsNotConfigured
+	}
+	if len(data) > MaxAvatarSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Check the format and dimensions before decoding the whole image
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidAvatar
+	}
+	contentType, ok := avatarContentTypes[format]
+	if !ok {
+		return nil, ErrUnsupportedAvatarType
+	}
+	if config.Width > MaxAvatarDimension || config.Height > MaxAvatarDimension {
+		return nil, ErrAvatarTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidAvatar
+	}
+
+	// Re-encode the image to drop metadata such as where a photo was taken.
+	// Only the first frame of animated GIFs is kept.
+	var original bytes.Buffer
+	ext := format
+	switch format {
+	case "jpeg":
+		ext = "jpg"
+		err = jpeg.Encode(&original, img, &jpeg.Options{Quality: 90})
+	case "png":
+		err = png.Encode(&original, img)
+	case "gif":
+		err = gif.Encode(&original, img, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail.Square(img, AvatarThumbnailSize)); err != nil {
+		return nil, err
+	}
+
+	// Store under new keys so that cached copies of the previous avatar are not reused
+	random, err := securetoken.New(12)
+	if err != nil {
+		return nil, err
+	}
+	avatar := &model.Avatar{
+		ContentType:  contentType,
+		Width:        config.Width,
+		Height:       config.Height,
+		Key:          fmt.Sprintf("avatars/%s/%s.%s", user.ID, random, ext),
+		ThumbnailKey: fmt.Sprintf("avatars/%s/%s-thumb.png", user.ID, random),
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.avatars.Put(ctx, avatar.Key, original.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := s.avatars.Put(ctx, avatar.ThumbnailKey, thumb.Bytes()); err != nil {
+		s.deleteAvatarBlobs(ctx, avatar)
+		return nil, err
+	}
+
+	previous := user.Profile.Avatar
+	user.Profile.Avatar = avatar
+	if err := s.repo.Update(ctx, user); err != nil {
+		s.deleteAvatarBlobs(ctx, avatar)
+		return nil, err
+	}
+	s.deleteAvatarBlobs(ctx, previous)
+
+	s.record(ctx, model.AuditUserUpdated, user.ID, map[string]string{"avatar": "changed"})
+
+	return user, nil
+}
+
+// GetAvatar returns the avatar of a user, or its square PNG thumbnail
+func (s *userService) GetAvatar(ctx context.Context, userID string, thumb bool) (*AvatarImage, error) {
+	if s.avatars == nil {
+		return nil, ErrAvatarsNotConfigured
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	avatar := user.Profile.Avatar
+	if avatar == nil {
+		return nil, ErrAvatarNotFound
+	}
+
+	key, contentType := avatar.Key, avatar.ContentType
+	if thumb {
+		key, contentType = avatar.ThumbnailKey, "image/png"
+	}
+
+	data, err := s.avatars.Get(ctx, key)
+	if err == ErrBlobNotFound {
+		return nil, ErrAvatarNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &AvatarImage{Data: data, ContentType: contentType, UpdatedAt: avatar.UpdatedAt}, nil
+}
+
+// DeleteAvatar removes the avatar of a user
+func (s *userService) DeleteAvatar(ctx context.Context, userID string) (*model.User, error) {
+	if s.avatars == nil {
+		return nil, ErrAvatarsNotConfigured
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	avatar := user.Profile.Avatar
+	if avatar == nil {
+		return nil, ErrAvatarNotFound
+	}
+
+	user.Profile.Avatar = nil
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	s.deleteAvatarBlobs(ctx, avatar)
+
+	s.record(ctx, model.AuditUserUpdated, user.ID, map[string]string{"avatar": "removed"})
+
+	return user, nil
+}
+
+// deleteAvatarBlobs removes the image and thumbnail of an avatar. Failures only leave
+// unused files behind, so they do not fail the request.
+func (s *userService) deleteAvatarBlobs(ctx context.Context, avatar *model.Avatar) {
+	if avatar == nil {
+		return
+	}
+	_ = s.avatars.Delete(ctx, avatar.Key)
+	_ = s.avatars.Delete(ctx, avatar.ThumbnailKey)
+}
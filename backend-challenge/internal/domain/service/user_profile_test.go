@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// Mock BlobStore for testing
+type mockBlobStore struct {
+	blobs map[string][]byte
+}
+
+func (m *mockBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	m.blobs[key] = data
+	return nil
+}
+
+func (m *mockBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return data, nil
+}
+
+func (m *mockBlobStore) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+// encodePNG returns a PNG image of the given size filled with one color
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Test UpdateUser validates and saves profile fields
+func TestUpdateUserProfile(t *testing.T) {
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	user, err := service.Register(context.Background(), &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: valid profile fields are trimmed and saved
+	displayName, locale, timezone, bio := " Tester ", "th-TH", "Asia/Bangkok", "Hello"
+	updated, err := service.UpdateUser(context.Background(), user.ID, &model.UpdateUserInput{
+		DisplayName: &displayName,
+		Locale:      &locale,
+		Timezone:    &timezone,
+		Bio:         &bio,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.Profile.DisplayName != "Tester" || updated.Profile.Locale != locale || updated.Profile.Timezone != timezone || updated.Profile.Bio != bio {
+		t.Errorf("Unexpected profile %+v", updated.Profile)
+	}
+
+	// Test case: invalid profile fields
+	long := string(make([]rune, 501))
+	invalidLocale, invalidTimezone, local := "english!", "Mars/Olympus", "Local"
+	tests := []struct {
+		input *model.UpdateUserInput
+		err   error
+	}{
+		{&model.UpdateUserInput{Locale: &invalidLocale}, ErrInvalidLocale},
+		{&model.UpdateUserInput{Timezone: &invalidTimezone}, ErrInvalidTimezone},
+		{&model.UpdateUserInput{Timezone: &local}, ErrInvalidTimezone},
+		{&model.UpdateUserInput{Bio: &long}, ErrInvalidBio},
+		{&model.UpdateUserInput{DisplayName: &long}, ErrInvalidDisplayName},
+	}
+	for _, tt := range tests {
+		if _, err := service.UpdateUser(context.Background(), user.ID, tt.input); err != tt.err {
+			t.Errorf("Expected error %v, got %v", tt.err, err)
+		}
+	}
+}
+
+// Test SetAvatar stores the image with a thumbnail and replaces the previous avatar
+func TestAvatar(t *testing.T) {
+	repo := newMockUserRepository()
+	store := &mockBlobStore{blobs: make(map[string][]byte)}
+	service := NewUserService(repo, WithAvatarStore(store))
+	user, err := service.Register(context.Background(), &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: no avatar yet
+	if _, err := service.GetAvatar(context.Background(), user.ID, false); err != ErrAvatarNotFound {
+		t.Errorf("Expected error %v, got %v", ErrAvatarNotFound, err)
+	}
+
+	// Test case: upload a wide image
+	updated, err := service.SetAvatar(context.Background(), user.ID, encodePNG(t, 300, 200))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	avatar := updated.Profile.Avatar
+	if avatar == nil || avatar.ContentType != "image/png" || avatar.Width != 300 || avatar.Height != 200 {
+		t.Fatalf("Unexpected avatar %+v", avatar)
+	}
+	thumb, err := service.GetAvatar(context.Background(), user.ID, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(thumb.Data))
+	if err != nil || config.Width != AvatarThumbnailSize || config.Height != AvatarThumbnailSize {
+		t.Errorf("Expected %dx%d PNG thumbnail, got %+v, %v", AvatarThumbnailSize, AvatarThumbnailSize, config, err)
+	}
+
+	// Test case: a new upload replaces the previous images
+	if _, err := service.SetAvatar(context.Background(), user.ID, encodePNG(t, 64, 64)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(store.blobs) != 2 {
+		t.Errorf("Expected previous avatar to be deleted, got %d blobs", len(store.blobs))
+	}
+
+	// Test case: invalid uploads
+	if _, err := service.SetAvatar(context.Background(), user.ID, []byte("not an image")); err != ErrInvalidAvatar {
+		t.Errorf("Expected error %v, got %v", ErrInvalidAvatar, err)
+	}
+	if _, err := service.SetAvatar(context.Background(), user.ID, encodePNG(t, MaxAvatarDimension+1, 1)); err != ErrAvatarTooLarge {
+		t.Errorf("Expected error %v, got %v", ErrAvatarTooLarge, err)
+	}
+
+	// Test case: delete the avatar
+	if _, err := service.DeleteAvatar(context.Background(), user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(store.blobs) != 0 {
+		t.Errorf("Expected avatar images to be deleted, got %d blobs", len(store.blobs))
+	}
+	if _, err := service.DeleteAvatar(context.Background(), user.ID); err != ErrAvatarNotFound {
+		t.Errorf("Expected error %v, got %v", ErrAvatarNotFound, err)
+	}
+}
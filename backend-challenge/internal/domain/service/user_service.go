@@ -2,11 +2,26 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
 
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/repository"
+	"backend-challenge/pkg/securetoken"
 )
 
+// Default lifetimes of emailed tokens
+const (
+	defaultPasswordResetTTL     = time.Hour
+	defaultEmailVerificationTTL = 24 * time.Hour
+)
+
+// passwordResetSendTimeout limits how long sending a password reset link may take
+const passwordResetSendTimeout = 30 * time.Second
+
 // User service errors are now defined in errors.go
 
 // UserService defines the user business logic service
@@ -23,26 +38,196 @@ type UserService interface {
 	// UpdateUser updates a user
 	UpdateUser(ctx context.Context, id string, input *model.UpdateUserInput) (*model.User, error)
 
-	// DeleteUser removes a user
+	// SetAvatar replaces the avatar of a user with a JPEG, PNG or GIF image
+	SetAvatar(ctx context.Context, userID string, data []byte) (*model.User, error)
+
+	// GetAvatar returns the avatar of a user, or its square thumbnail
+	GetAvatar(ctx context.Context, userID string, thumbnail bool) (*AvatarImage, error)
+
+	// DeleteAvatar removes the avatar of a user
+	DeleteAvatar(ctx context.Context, userID string) (*model.User, error)
+
+	// DeleteUser soft deletes a user, who can be restored until purged
 	DeleteUser(ctx context.Context, id string) error
 
-	// ListUsers returns all users with pagination
-	ListUsers(ctx context.Context, page, pageSize int) ([]*model.User, error)
+	// RestoreUser undoes the deletion of a user
+	RestoreUser(ctx context.Context, id string) (*model.User, error)
+
+	// ChangeRole changes the role of a user
+	ChangeRole(ctx context.Context, id string, role model.Role) (*model.User, error)
+
+	// ListUsers returns a page of the users matching the options with the total number of matches.
+	// The total is not counted for pages after a cursor.
+	ListUsers(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error)
 
 	// CountUsers returns the total number of users
 	CountUsers(ctx context.Context) (int64, error)
+
+	// ImportUsers creates users from the valid rows of a bulk import and reports the invalid ones.
+	// A dry run only validates the rows.
+	ImportUsers(ctx context.Context, rows []*model.ImportUserRow, dryRun bool) (*model.ImportResult, error)
+
+	// ExportUsers calls fn for every user, oldest first
+	ExportUsers(ctx context.Context, fn func(*model.User) error) error
+
+	// RequestPasswordReset emails a password reset link if the email belongs to a user
+	RequestPasswordReset(ctx context.Context, email string) error
+
+	// ResetPassword sets a new password using a password reset token
+	ResetPassword(ctx context.Context, input *model.ResetPasswordInput) (*model.User, error)
+
+	// ChangePassword sets a new password after checking the current one
+	ChangePassword(ctx context.Context, id string, input *model.ChangePasswordInput) (*model.User, error)
+
+	// VerifyEmail marks the email of a user as verified using a verification token
+	VerifyEmail(ctx context.Context, token string) (*model.User, error)
+
+	// ResendVerification emails a new verification link if the email belongs to an unverified user
+	ResendVerification(ctx context.Context, email string) error
+
+	// EnrollMFA generates a new TOTP secret for a user, active once confirmed
+	EnrollMFA(ctx context.Context, userID string) (*model.MFAEnrollment, error)
+
+	// ConfirmMFA enables two-factor authentication with a code from the enrolled secret
+	// and returns single-use recovery codes
+	ConfirmMFA(ctx context.Context, userID, code string) ([]string, error)
+
+	// DisableMFA turns off two-factor authentication after checking the password and a code
+	DisableMFA(ctx context.Context, userID string, input *model.MFADisableInput) error
+
+	// VerifyMFA checks the second factor of a login with a TOTP or recovery code
+	VerifyMFA(ctx context.Context, userID, code string) (*model.User, error)
 }
 
 // userService implements UserService
 type userService struct {
 	repo repository.UserRepository
+
+	passwordPolicy PasswordPolicy
+	hasher         model.PasswordHasher
+
+	// dummyHash is compared against for unknown users, created on first use
+	dummyHash     string
+	dummyHashOnce sync.Once
+
+	tokens           repository.OneTimeTokenRepository
+	mailer           Mailer
+	appURL           string
+	passwordResetTTL time.Duration
+
+	requireVerifiedEmail bool
+	verificationTTL      time.Duration
+
+	throttle LoginThrottler
+
+	mfaIssuer string
+
+	audit AuditLogger
+
+	avatars BlobStore
+
+	revoker TokenRevoker
+
+	// background tracks password reset emails that are still being sent
+	background sync.WaitGroup
+}
+
+// UserServiceOption configures optional features of the user service
+type UserServiceOption func(*userService)
+
+// WithPasswordPolicy sets the rules new passwords must follow
+func WithPasswordPolicy(policy PasswordPolicy) UserServiceOption {
+	return func(s *userService) {
+		s.passwordPolicy = policy
+	}
+}
+
+// WithPasswordHasher sets how new passwords are hashed. Passwords hashed with other
+// algorithms or settings keep working and are rehashed on the next login.
+func WithPasswordHasher(hasher model.PasswordHasher) UserServiceOption {
+	return func(s *userService) {
+		s.hasher = hasher
+	}
+}
+
+// WithEmailTokens enables flows that email single-use links to users.
+// Links point to appURL, the public URL of the application.
+func WithEmailTokens(tokens repository.OneTimeTokenRepository, mailer Mailer, appURL string) UserServiceOption {
+	return func(s *userService) {
+		s.tokens = tokens
+		s.mailer = mailer
+		s.appURL = appURL
+	}
+}
+
+// WithPasswordResetTTL sets how long password reset tokens stay valid
+func WithPasswordResetTTL(ttl time.Duration) UserServiceOption {
+	return func(s *userService) {
+		s.passwordResetTTL = ttl
+	}
+}
+
+// WithEmailVerification sets how long verification links stay valid and whether
+// users must verify their email before they can log in
+func WithEmailVerification(required bool, ttl time.Duration) UserServiceOption {
+	return func(s *userService) {
+		s.requireVerifiedEmail = required
+		s.verificationTTL = ttl
+	}
+}
+
+// WithLoginThrottle limits failed login attempts
+func WithLoginThrottle(throttle LoginThrottler) UserServiceOption {
+	return func(s *userService) {
+		s.throttle = throttle
+	}
+}
+
+// WithMFAIssuer sets the issuer name shown in authenticator apps
+func WithMFAIssuer(issuer string) UserServiceOption {
+	return func(s *userService) {
+		s.mfaIssuer = issuer
+	}
+}
+
+// WithAuditLog records security-relevant events such as logins and account changes
+func WithAuditLog(audit AuditLogger) UserServiceOption {
+	return func(s *userService) {
+		s.audit = audit
+	}
+}
+
+// WithAvatarStore enables avatar uploads, keeping the images in the given store
+func WithAvatarStore(store BlobStore) UserServiceOption {
+	return func(s *userService) {
+		s.avatars = store
+	}
+}
+
+// WithTokenRevoker signs users out when their access changes, so that tokens
+// issued before the change cannot be used any more
+func WithTokenRevoker(revoker TokenRevoker) UserServiceOption {
+	return func(s *userService) {
+		s.revoker = revoker
+	}
 }
 
 // NewUserService creates a new UserService
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{
-		repo: repo,
+func NewUserService(repo repository.UserRepository, opts ...UserServiceOption) UserService {
+	s := &userService{
+		repo:             repo,
+		passwordPolicy:   NewPasswordPolicy(DefaultPasswordPolicyConfig(), nil),
+		hasher:           model.DefaultPasswordHasher(),
+		passwordResetTTL: defaultPasswordResetTTL,
+		verificationTTL:  defaultEmailVerificationTTL,
+		mfaIssuer:        defaultMFAIssuer,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 // Register creates a new user
@@ -53,8 +238,12 @@ func (s *userService) Register(ctx context.Context, input *model.RegisterUserInp
 		return nil, ErrEmailExists
 	}
 
+	if err := s.passwordPolicy.Check(ctx, input.Password, input.Email, input.Name); err != nil {
+		return nil, err
+	}
+
 	// Create new user
-	user, err := model.NewUser(input)
+	user, err := model.NewUser(input, s.hasher)
 	if err != nil {
 		return nil, err
 	}
@@ -64,23 +253,118 @@ func (s *userService) Register(ctx context.Context, input *model.RegisterUserInp
 		return nil, err
 	}
 
+	s.recordAs(ctx, user.ID, model.AuditUserRegistered, user.ID, nil)
+
+	// Ask the user to confirm their email. A failed delivery does not undo the
+	// registration, the user can request a new link.
+	if s.tokens != nil {
+		_ = s.sendVerification(ctx, user)
+	}
+
 	return user, nil
 }
 
-// Login authenticates a user
+// Login authenticates a user. Unknown emails and wrong passwords return the same
+// error after the same amount of work so that accounts cannot be enumerated.
 func (s *userService) Login(ctx context.Context, input *model.LoginUserInput) (*model.User, error) {
+	ip := model.ClientInfoFromContext(ctx).IP
+
+	// Count the attempt up front and reject it while the account or client is locked out
+	if s.throttle != nil {
+		if err := s.throttle.Reserve(ctx, input.Email, ip); err != nil {
+			s.recordLoginFailure(ctx, "", input.Email, "locked")
+			return nil, err
+		}
+	}
+
 	user, err := s.repo.GetByEmail(ctx, input.Email)
 	if err != nil {
-		return nil, ErrUserNotFound
+		s.checkDummyPassword(input.Password)
+		s.recordLoginFailure(ctx, "", input.Email, "unknown_email")
+		return nil, ErrInvalidCredentials
 	}
 
-	if !user.CheckPassword(input.Password) {
-		return nil, ErrInvalidPassword
+	if !s.hasher.Verify(input.Password, user.Password) {
+		s.recordLoginFailure(ctx, user.ID, input.Email, "invalid_password")
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade hashes created with another algorithm or older settings while the
+	// password is at hand. A failed upgrade is retried on the next login.
+	if s.hasher.NeedsRehash(user.Password) {
+		if hashedPassword, err := s.hasher.Hash(input.Password); err == nil {
+			previous := user.Password
+			user.Password = hashedPassword
+			if err := s.repo.Update(ctx, user); err != nil {
+				user.Password = previous
+			}
+		}
+	}
+
+	if s.throttle != nil {
+		if err := s.throttle.RecordSuccess(ctx, input.Email, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only checked after the password so that it does not reveal the account state
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		s.recordLoginFailure(ctx, user.ID, input.Email, "email_not_verified")
+		return nil, ErrEmailNotVerified
+	}
+
+	// Logins with two-factor authentication succeed once the code is verified
+	if !user.MFA.Enabled {
+		s.recordAs(ctx, user.ID, model.AuditLoginSucceeded, user.ID, nil)
 	}
 
 	return user, nil
 }
 
+// record records an audit event for the target user if the audit log is enabled
+func (s *userService) record(ctx context.Context, action model.AuditAction, targetID string, details map[string]string) {
+	s.recordAs(ctx, "", action, targetID, details)
+}
+
+// recordAs records an audit event with an explicit actor, for requests that are not authenticated yet
+func (s *userService) recordAs(ctx context.Context, actorID string, action model.AuditAction, targetID string, details map[string]string) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Details:  details,
+	})
+}
+
+// recordLoginFailure records a failed login. The target is empty for unknown emails.
+func (s *userService) recordLoginFailure(ctx context.Context, userID, email, reason string) {
+	s.record(ctx, model.AuditLoginFailed, userID, map[string]string{
+		"email":  email,
+		"reason": reason,
+	})
+}
+
+// revokeTokens signs a user out of every session if a token revoker is configured
+func (s *userService) revokeTokens(ctx context.Context, userID string) error {
+	if s.revoker == nil {
+		return nil
+	}
+	return s.revoker.RevokeAllForUser(ctx, userID)
+}
+
+// checkDummyPassword runs a password comparison that always fails. It is used for
+// unknown users so that login takes the same time whether or not the user exists.
+func (s *userService) checkDummyPassword(password string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy-password")
+	})
+	_ = s.hasher.Verify(password, s.dummyHash)
+}
+
 // GetByID fetches a user by ID
 func (s *userService) GetByID(ctx context.Context, id string) (*model.User, error) {
 	if id == "" {
@@ -97,19 +381,37 @@ func (s *userService) GetByID(ctx context.Context, id string) (*model.User, erro
 
 // UpdateUser updates a user
 func (s *userService) UpdateUser(ctx context.Context, id string, input *model.UpdateUserInput) (*model.User, error) {
+	if err := validateProfile(input); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
 
 	// If email is being updated, check if it already exists
-	if input.Email != "" && input.Email != user.Email {
+	emailChanged := input.Email != "" && input.Email != user.Email
+	if emailChanged {
 		existingUser, _ := s.repo.GetByEmail(ctx, input.Email)
 		if existingUser != nil {
 			return nil, ErrEmailExists
 		}
 	}
 
+	// Remember what changes for the audit log
+	details := map[string]string{}
+	if input.Name != "" && input.Name != user.Name {
+		details["name"] = "changed"
+	}
+	if emailChanged {
+		details["previous_email"] = user.Email
+		details["email"] = input.Email
+	}
+	if input.DisplayName != nil || input.Locale != nil || input.Timezone != nil || input.Bio != nil {
+		details["profile"] = "changed"
+	}
+
 	// Update user fields
 	user.Update(input)
 
@@ -118,38 +420,388 @@ func (s *userService) UpdateUser(ctx context.Context, id string, input *model.Up
 		return nil, err
 	}
 
+	s.record(ctx, model.AuditUserUpdated, user.ID, details)
+
+	// Ask the user to confirm the new email, invalidating links sent to the old one.
+	// A failed delivery does not undo the change, the user can request a new link.
+	if emailChanged && s.tokens != nil {
+		_ = s.sendVerification(ctx, user)
+	}
+
 	return user, nil
 }
 
-// DeleteUser removes a user
+// DeleteUser soft deletes a user, who can be restored until purged
 func (s *userService) DeleteUser(ctx context.Context, id string) error {
 	if id == "" {
 		return ErrInvalidID
 	}
 
 	// Check if user exists
-	_, err := s.repo.GetByID(ctx, id)
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return ErrUserNotFound
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id, time.Now()); err != nil {
+		return err
+	}
+
+	// Deleted users cannot sign in, so end the sessions they already have
+	if err := s.revokeTokens(ctx, id); err != nil {
+		return err
+	}
+
+	s.record(ctx, model.AuditUserDeleted, id, map[string]string{"email": user.Email})
+
+	return nil
+}
+
+// RestoreUser undoes the deletion of a user
+func (s *userService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	// Fails if the user does not exist, is not deleted or was already purged
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	s.record(ctx, model.AuditUserRestored, id, map[string]string{"email": user.Email})
+
+	return user, nil
+}
+
+// ChangeRole changes the role of a user
+func (s *userService) ChangeRole(ctx context.Context, id string, role model.Role) (*model.User, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	previous := user.GetRole()
+	user.Role = role
+
+	// Save to repository
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Tokens carry the role they were issued with, so sign the user out to
+	// make the new role take effect immediately
+	if previous != role {
+		if err := s.revokeTokens(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.record(ctx, model.AuditRoleChanged, user.ID, map[string]string{
+		"previous_role": string(previous),
+		"role":          string(role),
+	})
+
+	return user, nil
 }
 
-// ListUsers returns all users with pagination
-func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*model.User, error) {
+// ListUsers returns a page of the users matching the options with the total number of matches.
+// Users are sorted by creation time, newest first, unless another order is given.
+// The total is only counted for offset pages, as cursor pages are meant to stay cheap.
+func (s *userService) ListUsers(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error) {
+	// Validate sort parameters
+	if opts.SortBy == "" {
+		opts.SortBy = model.UserSortCreatedAt
+	}
+	if !opts.SortBy.IsValid() {
+		return nil, 0, ErrInvalidSortField
+	}
+	if opts.Order == "" {
+		opts.Order = model.SortDesc
+	}
+	if !opts.Order.IsValid() {
+		return nil, 0, ErrInvalidSortOrder
+	}
+
+	// Cursors are keyed on creation time and ID, so they only work in that order
+	if opts.After != nil && opts.SortBy != model.UserSortCreatedAt {
+		return nil, 0, ErrCursorSortField
+	}
+
 	// Validate pagination parameters
-	if page < 1 {
-		page = 1
+	if opts.After != nil {
+		opts.Page = 0
+	} else if opts.Page < 1 {
+		opts.Page = 1
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if opts.PageSize < 1 || opts.PageSize > 100 {
+		opts.PageSize = 10
 	}
 
-	return s.repo.List(ctx, page, pageSize)
+	return s.repo.List(ctx, opts)
 }
 
 // CountUsers returns the total number of users
 func (s *userService) CountUsers(ctx context.Context) (int64, error) {
 	return s.repo.CountUsers(ctx)
-}
\ No newline at end of file
+}
+
+// RequestPasswordReset emails a password reset link if the email belongs to a user.
+// The link is sent in the background and unknown emails are not reported, so the
+// response neither says nor takes longer when an account exists.
+func (s *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	if s.tokens == nil {
+		return ErrMailNotConfigured
+	}
+
+	// The request may finish before the mail is sent, so do not use its context
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// sendPasswordReset emails a new password reset link if the email belongs to a user
+func (s *userService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	// Only the latest reset link stays valid
+	if err := s.tokens.DeleteByUser(ctx, user.ID, model.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token, err := s.issueOneTimeToken(ctx, user.ID, model.TokenPurposePasswordReset, s.passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	msg := &MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not request a password reset, you can ignore this email.\n",
+			user.Name, s.passwordResetTTL, s.tokenURL("/reset-password", token),
+		),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// ResetPassword sets a new password using a password reset token
+func (s *userService) ResetPassword(ctx context.Context, input *model.ResetPasswordInput) (*model.User, error) {
+	if s.tokens == nil {
+		return nil, ErrMailNotConfigured
+	}
+
+	tokenHash := securetoken.Hash(input.Token)
+	token, err := s.tokens.GetValid(ctx, model.TokenPurposePasswordReset, tokenHash, time.Now())
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	user, err := s.repo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	// Checked before consuming the token so that the user can try another password
+	if err := s.passwordPolicy.Check(ctx, input.Password, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	// Consuming the token fails if it was used in the meantime
+	if _, err := s.tokens.Consume(ctx, model.TokenPurposePasswordReset, tokenHash, time.Now()); err != nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	hashedPassword, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hashedPassword
+
+	// Save to repository
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Invalidate any other outstanding reset links
+	if err := s.tokens.DeleteByUser(ctx, user.ID, model.TokenPurposePasswordReset); err != nil {
+		return nil, err
+	}
+
+	s.recordAs(ctx, user.ID, model.AuditPasswordReset, user.ID, nil)
+
+	return user, nil
+}
+
+// ChangePassword sets a new password after checking the current one
+func (s *userService) ChangePassword(ctx context.Context, id string, input *model.ChangePasswordInput) (*model.User, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Wrong current passwords count as failed logins, so that a stolen session
+	// cannot be used to guess the password without being locked out
+	ip := model.ClientInfoFromContext(ctx).IP
+	if s.throttle != nil {
+		if err := s.throttle.Reserve(ctx, user.Email, ip); err != nil {
+			return nil, err
+		}
+	}
+	if !s.hasher.Verify(input.CurrentPassword, user.Password) {
+		return nil, ErrInvalidPassword
+	}
+	if s.throttle != nil {
+		if err := s.throttle.RecordSuccess(ctx, user.Email, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.passwordPolicy.Check(ctx, input.NewPassword, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hashedPassword
+
+	// Save to repository
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, model.AuditPasswordChanged, user.ID, nil)
+
+	return user, nil
+}
+
+// VerifyEmail marks the email of a user as verified using a verification token
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	if s.tokens == nil {
+		return nil, ErrMailNotConfigured
+	}
+
+	// Consuming the token fails if it is unknown, expired or already used
+	stored, err := s.tokens.Consume(ctx, model.TokenPurposeEmailVerification, securetoken.Hash(token), time.Now())
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.repo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+
+		// Save to repository
+		if err := s.repo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// Invalidate any other outstanding verification links
+	if err := s.tokens.DeleteByUser(ctx, user.ID, model.TokenPurposeEmailVerification); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification emails a new verification link if the email belongs to an unverified user.
+// Unknown and already verified emails are not reported so that accounts cannot be enumerated.
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	if s.tokens == nil {
+		return ErrMailNotConfigured
+	}
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// sendVerification emails a new verification link to a user, invalidating earlier links
+func (s *userService) sendVerification(ctx context.Context, user *model.User) error {
+	if err := s.tokens.DeleteByUser(ctx, user.ID, model.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := s.issueOneTimeToken(ctx, user.ID, model.TokenPurposeEmailVerification, s.verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	msg := &MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Name, s.verificationTTL, s.tokenURL("/api/auth/verify", token),
+		),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// issueOneTimeToken creates and stores a new single-use token for a user
+func (s *userService) issueOneTimeToken(ctx context.Context, userID string, purpose model.TokenPurpose, ttl time.Duration) (string, error) {
+	token, err := securetoken.New(securetoken.DefaultSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	stored := &model.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: securetoken.Hash(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	if err := s.tokens.Create(ctx, stored); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// tokenURL builds a link to the application carrying a one-time token
+func (s *userService) tokenURL(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
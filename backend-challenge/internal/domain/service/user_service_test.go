@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/repository"
+	"backend-challenge/pkg/cursor"
 )
 
 // Mock UserRepository for testing
@@ -15,6 +18,9 @@ type mockUserRepository struct {
 	users map[string]*model.User
 }
 
+// Ensure the mock satisfies the repository interface
+var _ repository.UserRepository = (*mockUserRepository)(nil)
+
 func newMockUserRepository() *mockUserRepository {
 	return &mockUserRepository{
 		users: make(map[string]*model.User),
@@ -44,9 +50,19 @@ func (m *mockUserRepository) Create(ctx context.Context, user *model.User) error
 	return nil
 }
 
+func (m *mockUserRepository) CreateMany(ctx context.Context, users []*model.User) ([]int, error) {
+	var duplicates []int
+	for i, user := range users {
+		if err := m.Create(ctx, user); err != nil {
+			duplicates = append(duplicates, i)
+		}
+	}
+	return duplicates, nil
+}
+
 func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	user, ok := m.users[id]
-	if !ok {
+	if !ok || user.IsDeleted() {
 		return nil, errors.New("user not found")
 	}
 	return user, nil
@@ -54,7 +70,7 @@ func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*model.Use
 
 func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	for _, user := range m.users {
-		if user.Email == email {
+		if user.Email == email && !user.IsDeleted() {
 			return user, nil
 		}
 	}
@@ -70,31 +86,167 @@ func (m *mockUserRepository) Update(ctx context.Context, user *model.User) error
 	return nil
 }
 
-func (m *mockUserRepository) Delete(ctx context.Context, id string) error {
-	_, ok := m.users[id]
-	if !ok {
+func (m *mockUserRepository) UpdateMFA(ctx context.Context, id string, previous, mfa model.MFA) (bool, error) {
+	user, ok := m.users[id]
+	if !ok || user.IsDeleted() || !user.MFA.Equal(previous) {
+		return false, nil
+	}
+	user.MFA = mfa
+	return true, nil
+}
+
+func (m *mockUserRepository) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	user, ok := m.users[id]
+	if !ok || user.IsDeleted() {
 		return errors.New("user not found")
 	}
+	user.DeletedAt = &deletedAt
+	return nil
+}
+
+func (m *mockUserRepository) Erase(ctx context.Context, id string) (bool, error) {
+	if _, ok := m.users[id]; !ok {
+		return false, nil
+	}
 	delete(m.users, id)
+	return true, nil
+}
+
+func (m *mockUserRepository) Restore(ctx context.Context, id string) error {
+	user, ok := m.users[id]
+	if !ok || !user.IsDeleted() {
+		return errors.New("user not found")
+	}
+	user.DeletedAt = nil
 	return nil
 }
 
-func (m *mockUserRepository) List(ctx context.Context, page, pageSize int) ([]*model.User, error) {
+func (m *mockUserRepository) ListDeleted(ctx context.Context, before time.Time) ([]*model.User, error) {
 	var users []*model.User
 	for _, user := range m.users {
-		users = append(users, user)
+		if user.IsDeleted() && user.DeletedAt.Before(before) {
+			users = append(users, user)
+		}
 	}
 	return users, nil
 }
 
+func (m *mockUserRepository) List(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error) {
+	var users []*model.User
+	for _, user := range m.users {
+		if !user.IsDeleted() && opts.Matches(user) {
+			users = append(users, user)
+		}
+	}
+	var total int64
+	if opts.After == nil {
+		total = int64(len(users))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return opts.Less(users[i], users[j])
+	})
+	page := []*model.User{}
+	for _, user := range users {
+		if opts.Follows(user) {
+			page = append(page, user)
+		}
+	}
+	return page, total, nil
+}
+
 func (m *mockUserRepository) CountUsers(ctx context.Context) (int64, error) {
-	return int64(len(m.users)), nil
+	var count int64
+	for _, user := range m.users {
+		if !user.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
 }
 
 func (m *mockUserRepository) Disconnect(ctx context.Context) error {
 	return nil
 }
 
+// Mock OneTimeTokenRepository for testing
+type mockOneTimeTokenRepository struct {
+	tokens []*model.OneTimeToken
+}
+
+func (m *mockOneTimeTokenRepository) Create(ctx context.Context, token *model.OneTimeToken) error {
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *mockOneTimeTokenRepository) GetValid(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose && !token.IsUsed() && !token.IsExpired(now) {
+			return token, nil
+		}
+	}
+	return nil, errors.New("token not found")
+}
+
+func (m *mockOneTimeTokenRepository) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose && !token.IsUsed() && !token.IsExpired(now) {
+			token.UsedAt = now
+			return token, nil
+		}
+	}
+	return nil, errors.New("token not found")
+}
+
+func (m *mockOneTimeTokenRepository) DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error {
+	var kept []*model.OneTimeToken
+	for _, token := range m.tokens {
+		if token.UserID != userID || token.Purpose != purpose {
+			kept = append(kept, token)
+		}
+	}
+	m.tokens = kept
+	return nil
+}
+
+// Mock Mailer recording sent messages
+type mockMailer struct {
+	sent []*MailMessage
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg *MailMessage) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// tokenFromMail extracts the token from the link in the last sent message
+func (m *mockMailer) tokenFromMail(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatalf("Expected a message to be sent")
+	}
+	body := m.sent[len(m.sent)-1].Body
+	start := strings.Index(body, "token=")
+	if start < 0 {
+		t.Fatalf("Expected a token link in message, got %q", body)
+	}
+	return strings.Fields(body[start+len("token="):])[0]
+}
+
+// waitForBackground waits until the emails sent in the background are delivered
+func waitForBackground(service UserService) {
+	service.(*userService).background.Wait()
+}
+
+// Mock TokenRevoker recording the users signed out
+type mockTokenRevoker struct {
+	revoked []string
+}
+
+func (m *mockTokenRevoker) RevokeAllForUser(ctx context.Context, userID string) error {
+	m.revoked = append(m.revoked, userID)
+	return nil
+}
+
 // Test RegisterUser
 func TestRegisterUser(t *testing.T) {
 	// Create mock repository
@@ -160,16 +312,57 @@ func TestLogin(t *testing.T) {
 	// Test case: user not found
 	loginInput.Email = "nonexistent@example.com"
 	_, err = service.Login(context.Background(), loginInput)
-	if err != ErrUserNotFound {
-		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
+	if err != ErrInvalidCredentials {
+		t.Errorf("Expected error %v, got %v", ErrInvalidCredentials, err)
 	}
 
 	// Test case: invalid password
 	loginInput.Email = "test@example.com"
 	loginInput.Password = "wrongpassword"
 	_, err = service.Login(context.Background(), loginInput)
-	if err != ErrInvalidPassword {
-		t.Errorf("Expected error %v, got %v", ErrInvalidPassword, err)
+	if err != ErrInvalidCredentials {
+		t.Errorf("Expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+}
+
+// Test Login rehashes passwords created with older settings
+func TestLoginRehash(t *testing.T) {
+	repo := newMockUserRepository()
+
+	// Create a user with a cheap bcrypt hash
+	oldService := NewUserService(repo, WithPasswordHasher(model.NewBcryptHasher(4)))
+	user, err := oldService.Register(context.Background(), &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Switch to argon2id
+	hasher := model.NewArgon2idHasher(model.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+	service := NewUserService(repo, WithPasswordHasher(hasher))
+
+	loginInput := &model.LoginUserInput{
+		Email:    "test@example.com",
+		Password: "password123",
+	}
+	if _, err := service.Login(context.Background(), loginInput); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stored, _ := repo.GetByID(context.Background(), user.ID)
+	if !strings.HasPrefix(stored.Password, "$argon2id$") {
+		t.Errorf("Expected password to be rehashed with argon2id, got %s", stored.Password)
+	}
+	if hasher.NeedsRehash(stored.Password) {
+		t.Errorf("Expected rehashed password to use the current settings")
+	}
+
+	// Test case: the upgraded hash keeps working
+	if _, err := service.Login(context.Background(), loginInput); err != nil {
+		t.Errorf("Expected no error, got %v", err)
 	}
 }
 
@@ -228,13 +421,65 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+// Test UpdateUser with a new email
+func TestUpdateUserEmail(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service with email tokens
+	repo := newMockUserRepository()
+	mailer := &mockMailer{}
+	service := NewUserService(repo, WithEmailTokens(&mockOneTimeTokenRepository{}, mailer, "http://app.test"))
+
+	// Create a verified user
+	user, _ := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	oldToken := mailer.tokenFromMail(t)
+	if _, err := service.VerifyEmail(ctx, oldToken); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mailer.sent = nil
+
+	// Test case: a new email has to be verified again
+	updatedUser, err := service.UpdateUser(ctx, user.ID, &model.UpdateUserInput{Email: "updated@example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updatedUser.EmailVerified {
+		t.Errorf("Expected email to be unverified after the change")
+	}
+
+	// Test case: a verification link is sent to the new email
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "updated@example.com" {
+		t.Fatalf("Expected a verification message to updated@example.com, got %v", mailer.sent)
+	}
+	if _, err := service.VerifyEmail(ctx, mailer.tokenFromMail(t)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: keeping the same email does not reset the verification
+	updatedUser, err = service.UpdateUser(ctx, user.ID, &model.UpdateUserInput{Name: "Updated Name", Email: "updated@example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !updatedUser.EmailVerified {
+		t.Errorf("Expected email to stay verified")
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("Expected no new message, got %d", len(mailer.sent))
+	}
+}
+
 // Test DeleteUser
 func TestDeleteUser(t *testing.T) {
 	// Create mock repository
 	repo := newMockUserRepository()
 
 	// Create service
-	service := NewUserService(repo)
+	revoker := &mockTokenRevoker{}
+	service := NewUserService(repo, WithTokenRevoker(revoker))
 
 	// Create a user
 	input := &model.RegisterUserInput{
@@ -250,6 +495,11 @@ func TestDeleteUser(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
+	// Verify the user was signed out
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != user.ID {
+		t.Errorf("Expected tokens of %s to be revoked, got %v", user.ID, revoker.revoked)
+	}
+
 	// Verify user was deleted
 	_, err = service.GetByID(context.Background(), user.ID)
 	if err != ErrUserNotFound {
@@ -261,4 +511,426 @@ func TestDeleteUser(t *testing.T) {
 	if err != ErrUserNotFound {
 		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
 	}
-}
\ No newline at end of file
+
+	// Test case: deleted users are hidden from login, listing and counts
+	_, err = service.Login(context.Background(), &model.LoginUserInput{Email: input.Email, Password: input.Password})
+	if err != ErrInvalidCredentials {
+		t.Errorf("Expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+	if count, _ := service.CountUsers(context.Background()); count != 0 {
+		t.Errorf("Expected 0 users, got %d", count)
+	}
+
+	// Test case: the email stays taken until the user is purged
+	if _, err := service.Register(context.Background(), input); err == nil {
+		t.Error("Expected registering a deleted user's email to fail")
+	}
+}
+
+// Test RestoreUser
+func TestRestoreUser(t *testing.T) {
+	// Create mock repository
+	repo := newMockUserRepository()
+
+	// Create service
+	service := NewUserService(repo)
+	ctx := context.Background()
+
+	// Create and delete a user
+	input := &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	}
+	user, _ := service.Register(ctx, input)
+
+	// Test case: users that are not deleted cannot be restored
+	if _, err := service.RestoreUser(ctx, user.ID); err != ErrUserNotFound {
+		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
+	}
+
+	if err := service.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: successful restore
+	restored, err := service.RestoreUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if restored.IsDeleted() {
+		t.Error("Expected restored user not to be deleted")
+	}
+	if _, err := service.Login(ctx, &model.LoginUserInput{Email: input.Email, Password: input.Password}); err != nil {
+		t.Errorf("Expected restored user to log in, got %v", err)
+	}
+
+}
+
+// Test ListUsers searches, filters and sorts users
+func TestListUsers(t *testing.T) {
+	// Create mock repository with users created a day apart
+	repo := newMockUserRepository()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, user := range []*model.User{
+		{ID: "user-1", Name: "Alice Smith", Email: "alice@example.com"},
+		{ID: "user-2", Name: "Bob Jones", Email: "bob@smith.org"},
+		{ID: "user-3", Name: "Carol White", Email: "carol@example.com"},
+	} {
+		user.CreatedAt = start.Add(time.Duration(i) * 24 * time.Hour)
+		repo.users[user.ID] = user
+	}
+
+	// Create service
+	service := NewUserService(repo)
+
+	tests := []struct {
+		name string
+		opts model.UserListOptions
+		want []string
+	}{
+		{"newest first by default", model.UserListOptions{}, []string{"user-3", "user-2", "user-1"}},
+		{"search name or email", model.UserListOptions{Search: "SMITH"}, []string{"user-2", "user-1"}},
+		{"prefix search", model.UserListOptions{Search: "smith", Prefix: true}, nil},
+		{"prefix search on email", model.UserListOptions{Search: "car", Prefix: true}, []string{"user-3"}},
+		{"created range", model.UserListOptions{CreatedFrom: start.Add(24 * time.Hour), CreatedTo: start.Add(48 * time.Hour)}, []string{"user-2"}},
+		{"sort by name", model.UserListOptions{SortBy: model.UserSortName, Order: model.SortAsc}, []string{"user-1", "user-2", "user-3"}},
+		{"sort by email descending", model.UserListOptions{SortBy: model.UserSortEmail}, []string{"user-3", "user-2", "user-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			users, total, err := service.ListUsers(context.Background(), &opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if total != int64(len(tt.want)) {
+				t.Errorf("Expected total %d, got %d", len(tt.want), total)
+			}
+			var ids []string
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected users %v, got %v", tt.want, ids)
+			}
+		})
+	}
+
+	// Test case: invalid sort parameters
+	if _, _, err := service.ListUsers(context.Background(), &model.UserListOptions{SortBy: "password"}); err != ErrInvalidSortField {
+		t.Errorf("Expected error %v, got %v", ErrInvalidSortField, err)
+	}
+	if _, _, err := service.ListUsers(context.Background(), &model.UserListOptions{Order: "up"}); err != ErrInvalidSortOrder {
+		t.Errorf("Expected error %v, got %v", ErrInvalidSortOrder, err)
+	}
+}
+
+// Test ListUsers continues after a cursor
+func TestListUsersAfterCursor(t *testing.T) {
+	// Create mock repository with two users created at the same time
+	repo := newMockUserRepository()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, user := range []*model.User{
+		{ID: "user-1", CreatedAt: start},
+		{ID: "user-2", CreatedAt: start.Add(time.Hour)},
+		{ID: "user-3", CreatedAt: start.Add(time.Hour)},
+	} {
+		repo.users[user.ID] = user
+	}
+
+	// Create service
+	service := NewUserService(repo)
+
+	// Test case: newest first, the first page ends with a cursor
+	opts := &model.UserListOptions{PageSize: 1}
+	users, _, err := service.ListUsers(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	next := opts.NextCursor(users[:1])
+	if next == "" {
+		t.Fatal("Expected a next cursor")
+	}
+
+	// Test case: the next page starts after the cursor, breaking the tie by ID
+	after, err := cursor.Decode(next)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	opts = &model.UserListOptions{After: &after, PageSize: 10}
+	users, total, err := service.ListUsers(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total != 0 {
+		t.Errorf("Expected cursor pages not to be counted, got %d", total)
+	}
+	if len(users) != 2 || users[0].ID != "user-2" || users[1].ID != "user-1" {
+		t.Errorf("Expected users user-2 and user-1, got %v", users)
+	}
+	if opts.NextCursor(users) != "" {
+		t.Error("Expected no next cursor after the last page")
+	}
+
+	// Test case: oldest first
+	opts = &model.UserListOptions{After: &after, Order: model.SortAsc}
+	users, _, err = service.ListUsers(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no users after the newest, got %v", users)
+	}
+
+	// Test case: cursors only work when sorted by creation time
+	opts = &model.UserListOptions{After: &after, SortBy: model.UserSortName}
+	if _, _, err := service.ListUsers(context.Background(), opts); err != ErrCursorSortField {
+		t.Errorf("Expected error %v, got %v", ErrCursorSortField, err)
+	}
+}
+
+// Test ChangeRole
+func TestChangeRole(t *testing.T) {
+	// Create mock repository
+	repo := newMockUserRepository()
+
+	// Create service
+	revoker := &mockTokenRevoker{}
+	service := NewUserService(repo, WithTokenRevoker(revoker))
+
+	// Create a user
+	input := &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	}
+	user, _ := service.Register(context.Background(), input)
+	if user.Role != model.RoleUser {
+		t.Errorf("Expected role %s, got %s", model.RoleUser, user.Role)
+	}
+
+	// Test case: successful promotion
+	promotedUser, err := service.ChangeRole(context.Background(), user.ID, model.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !promotedUser.IsAdmin() {
+		t.Errorf("Expected user to be an admin")
+	}
+
+	// Test case: the user is signed out so that the new role takes effect
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != user.ID {
+		t.Errorf("Expected tokens of %s to be revoked, got %v", user.ID, revoker.revoked)
+	}
+
+	// Test case: setting the same role again keeps the user signed in
+	if _, err := service.ChangeRole(context.Background(), user.ID, model.RoleAdmin); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(revoker.revoked) != 1 {
+		t.Errorf("Expected no further revocation, got %v", revoker.revoked)
+	}
+
+	// Test case: invalid role
+	_, err = service.ChangeRole(context.Background(), user.ID, model.Role("superuser"))
+	if err != ErrInvalidRole {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRole, err)
+	}
+
+	// Test case: user not found
+	_, err = service.ChangeRole(context.Background(), "nonexistent-id", model.RoleAdmin)
+	if err != ErrUserNotFound {
+		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
+	}
+}
+
+// Test ChangePassword
+func TestChangePassword(t *testing.T) {
+	// Create mock repository
+	repo := newMockUserRepository()
+
+	// Create service
+	service := NewUserService(repo, WithPasswordPolicy(NewPasswordPolicy(PasswordPolicyConfig{MinLength: 10}, nil)))
+
+	// Create a user
+	input := &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	}
+	user, _ := service.Register(context.Background(), input)
+
+	// Test case: wrong current password
+	_, err := service.ChangePassword(context.Background(), user.ID, &model.ChangePasswordInput{
+		CurrentPassword: "wrongpassword",
+		NewPassword:     "newpassword123",
+	})
+	if err != ErrInvalidPassword {
+		t.Errorf("Expected error %v, got %v", ErrInvalidPassword, err)
+	}
+
+	// Test case: password shorter than the configured minimum
+	_, err = service.ChangePassword(context.Background(), user.ID, &model.ChangePasswordInput{
+		CurrentPassword: "password123",
+		NewPassword:     "short1234",
+	})
+	if !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected error %v, got %v", ErrWeakPassword, err)
+	}
+
+	// Test case: successful change
+	updatedUser, err := service.ChangePassword(context.Background(), user.ID, &model.ChangePasswordInput{
+		CurrentPassword: "password123",
+		NewPassword:     "newpassword123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !updatedUser.CheckPassword("newpassword123") || updatedUser.CheckPassword("password123") {
+		t.Errorf("Expected only the new password to be accepted")
+	}
+
+	// Test case: user not found
+	_, err = service.ChangePassword(context.Background(), "nonexistent-id", &model.ChangePasswordInput{
+		CurrentPassword: "password123",
+		NewPassword:     "newpassword123",
+	})
+	if err != ErrUserNotFound {
+		t.Errorf("Expected error %v, got %v", ErrUserNotFound, err)
+	}
+}
+
+// Test PasswordReset
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service with email tokens
+	repo := newMockUserRepository()
+	mailer := &mockMailer{}
+	service := NewUserService(repo, WithEmailTokens(&mockOneTimeTokenRepository{}, mailer, "http://app.test"))
+
+	user, _ := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	mailer.sent = nil // Ignore the verification email
+
+	// Test case: unknown email does not send a message or fail
+	if err := service.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitForBackground(service)
+	if len(mailer.sent) != 0 {
+		t.Errorf("Expected no message, got %d", len(mailer.sent))
+	}
+
+	// Test case: a newer request invalidates the previous link
+	if err := service.RequestPasswordReset(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitForBackground(service)
+	oldToken := mailer.tokenFromMail(t)
+	if err := service.RequestPasswordReset(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitForBackground(service)
+	token := mailer.tokenFromMail(t)
+	if mailer.sent[1].To != user.Email {
+		t.Errorf("Expected message to %s, got %s", user.Email, mailer.sent[1].To)
+	}
+	_, err := service.ResetPassword(ctx, &model.ResetPasswordInput{Token: oldToken, Password: "newpassword123"})
+	if err != ErrInvalidResetToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidResetToken, err)
+	}
+
+	// Test case: weak password
+	_, err = service.ResetPassword(ctx, &model.ResetPasswordInput{Token: token, Password: "short"})
+	if !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected error %v, got %v", ErrWeakPassword, err)
+	}
+
+	// Test case: successful reset
+	if _, err := service.ResetPassword(ctx, &model.ResetPasswordInput{Token: token, Password: "newpassword123"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := service.Login(ctx, &model.LoginUserInput{Email: user.Email, Password: "newpassword123"}); err != nil {
+		t.Errorf("Expected login with new password to succeed, got %v", err)
+	}
+
+	// Test case: token cannot be used twice
+	_, err = service.ResetPassword(ctx, &model.ResetPasswordInput{Token: token, Password: "anotherpassword"})
+	if err != ErrInvalidResetToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidResetToken, err)
+	}
+
+	// Test case: not configured
+	_, err = NewUserService(repo).ResetPassword(ctx, &model.ResetPasswordInput{Token: token, Password: "newpassword123"})
+	if err != ErrMailNotConfigured {
+		t.Errorf("Expected error %v, got %v", ErrMailNotConfigured, err)
+	}
+}
+
+// Test EmailVerification
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	// Create service that requires a verified email for login
+	repo := newMockUserRepository()
+	mailer := &mockMailer{}
+	service := NewUserService(
+		repo,
+		WithEmailTokens(&mockOneTimeTokenRepository{}, mailer, "http://app.test"),
+		WithEmailVerification(true, time.Hour),
+	)
+
+	// Test case: registration sends a verification link
+	user, err := service.Register(ctx, &model.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.EmailVerified {
+		t.Errorf("Expected new user to be unverified")
+	}
+	if !strings.Contains(mailer.sent[0].Body, "http://app.test/api/auth/verify?token=") {
+		t.Errorf("Expected verification link, got %q", mailer.sent[0].Body)
+	}
+	token := mailer.tokenFromMail(t)
+
+	// Test case: login is blocked until verified
+	loginInput := &model.LoginUserInput{Email: user.Email, Password: "password123"}
+	if _, err := service.Login(ctx, loginInput); err != ErrEmailNotVerified {
+		t.Errorf("Expected error %v, got %v", ErrEmailNotVerified, err)
+	}
+
+	// Test case: invalid token
+	if _, err := service.VerifyEmail(ctx, "invalid-token"); err != ErrInvalidVerificationToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidVerificationToken, err)
+	}
+
+	// Test case: successful verification
+	verifiedUser, err := service.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !verifiedUser.EmailVerified {
+		t.Errorf("Expected user to be verified")
+	}
+	if _, err := service.Login(ctx, loginInput); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// Test case: verified users do not get new links
+	if err := service.ResendVerification(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("Expected no new message, got %d messages", len(mailer.sent))
+	}
+}
@@ -1,13 +1,17 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+	"backend-challenge/internal/domain/service"
+	repo "backend-challenge/internal/infrastructure/repository"
+	"backend-challenge/pkg/securetoken"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -17,13 +21,83 @@ var (
 	ErrTokenExpired     = errors.New("token expired")
 	ErrMissingToken     = errors.New("missing token")
 	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenRevoked     = errors.New("token revoked")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshNotEnabled   = errors.New("refresh tokens are not enabled")
+
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	ErrSessionNotFound = errors.New("session not found")
 )
 
-// JWTClaims represents the JWT claims
+// sessionTouchInterval limits how often the last-seen time of a session is written
+const sessionTouchInterval = time.Minute
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// JWTClaims represents the JWT claims. The token ID (jti) is carried in RegisteredClaims.ID.
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`     // Refresh token family the token was issued with
+	Purpose   string `json:"purpose,omitempty"` // Set on tokens that are not access tokens
 	jwt.RegisteredClaims
+
+	// Set when the request was authenticated with an API key instead of a JWT
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// HasScope reports whether the credentials grant the scope. Tokens from a user
+// login grant every scope, API keys only the scopes they were created with.
+func (c *JWTClaims) HasScope(scope string) bool {
+	return c.Principal().HasScope(scope)
+}
+
+// Principal returns the authenticated caller described by the claims
+func (c *JWTClaims) Principal() model.Principal {
+	return model.Principal{
+		UserID:    c.UserID,
+		Email:     c.Email,
+		Role:      c.GetRole(),
+		Scopes:    c.Scopes,
+		TokenID:   c.ID,
+		SessionID: c.SessionID,
+		APIKeyID:  c.APIKeyID,
+	}
+}
+
+// APIKeyVerifier checks API keys and returns the key with its owner
+type APIKeyVerifier interface {
+	Verify(ctx context.Context, key string) (*model.APIKey, *model.User, error)
+}
+
+// Token purposes
+const (
+	// PurposeMFA marks a token proving the password step of a login with two-factor authentication
+	PurposeMFA = "mfa"
+
+	// mfaTokenDuration is how long a user has to enter the second factor
+	mfaTokenDuration = 5 * time.Minute
+)
+
+// GetRole returns the role carried by the token. Tokens issued before roles existed belong to regular users.
+func (c *JWTClaims) GetRole() model.Role {
+	if c.Role == "" {
+		return model.RoleUser
+	}
+	return model.Role(c.Role)
+}
+
+// TokenPair represents an access token together with its refresh token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
 }
 
 // AuthService defines the authentication service
@@ -31,37 +105,167 @@ type AuthService interface {
 	// GenerateToken generates a JWT token for a user
 	GenerateToken(user *model.User) (string, error)
 
-	// ValidateToken validates a JWT token
-	ValidateToken(tokenString string) (*JWTClaims, error)
+	// GenerateTokenPair generates an access token and starts a new refresh token family.
+	// It fails with service.ErrEmailNotVerified for unverified users when verification is required.
+	GenerateTokenPair(ctx context.Context, user *model.User) (*TokenPair, error)
+
+	// RefreshTokens rotates a refresh token and issues a new token pair
+	RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, *model.User, error)
+
+	// ValidateToken validates a JWT token and checks it against the revocation list
+	ValidateToken(ctx context.Context, tokenString string) (*JWTClaims, error)
+
+	// GenerateMFAToken generates a short-lived token for completing a login with a second factor
+	GenerateMFAToken(user *model.User) (string, time.Duration, error)
+
+	// ValidateMFAToken validates a token issued by GenerateMFAToken
+	ValidateMFAToken(ctx context.Context, tokenString string) (*JWTClaims, error)
+
+	// RevokeToken revokes an access token and the refresh token family it belongs to
+	RevokeToken(ctx context.Context, claims *JWTClaims) error
+
+	// RevokePrincipalToken revokes the access token a principal authenticated with and its session
+	RevokePrincipalToken(ctx context.Context, principal model.Principal) error
+
+	// RevokeAllForUser revokes every access and refresh token of a user
+	RevokeAllForUser(ctx context.Context, userID string) error
+
+	// ListSessions returns the active login sessions of a user
+	ListSessions(ctx context.Context, userID string) ([]*model.Session, error)
+
+	// RevokeSession signs a user out of one session
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+
+	// RevokeOtherSessions signs a user out of every session except the current one
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID string) error
 
 	// ExtractTokenFromRequest extracts a token from an HTTP request
 	ExtractTokenFromRequest(r *http.Request) (string, error)
+
+	// ValidateAPIKey checks an API key and returns claims for its owner and scopes
+	ValidateAPIKey(ctx context.Context, key string) (*JWTClaims, error)
+
+	// Authenticate authenticates a request with an API key header or a bearer token
+	Authenticate(r *http.Request) (*JWTClaims, error)
+
+	// JWKS returns the public keys that can be used to verify tokens
+	JWKS() *JWKS
 }
 
 // jwtAuthService implements the AuthService interface
 type jwtAuthService struct {
-	secretKey     string
+	keys          *KeySet
 	tokenDuration time.Duration
+
+	refreshTokens   repository.RefreshTokenRepository
+	users           repository.UserRepository
+	refreshDuration time.Duration
+
+	revocations repository.TokenRevocationRepository
+
+	apiKeys APIKeyVerifier
+
+	sessions repository.SessionRepository
+
+	audit service.AuditLogger
+
+	requireVerifiedEmail bool
+}
+
+// Option configures optional features of the JWT auth service
+type Option func(*jwtAuthService)
+
+// WithKeySet signs tokens with the given key set instead of the shared secret
+func WithKeySet(keys *KeySet) Option {
+	return func(s *jwtAuthService) {
+		s.keys = keys
+	}
+}
+
+// WithRefreshTokens enables refresh tokens stored in the given repository.
+// The user repository is used to load the user when a refresh token is exchanged.
+func WithRefreshTokens(tokens repository.RefreshTokenRepository, users repository.UserRepository, duration time.Duration) Option {
+	return func(s *jwtAuthService) {
+		s.refreshTokens = tokens
+		s.users = users
+		s.refreshDuration = duration
+	}
+}
+
+// WithRevocationList enables server-side revocation of access tokens
+func WithRevocationList(revocations repository.TokenRevocationRepository) Option {
+	return func(s *jwtAuthService) {
+		s.revocations = revocations
+	}
+}
+
+// WithAPIKeys enables authentication with API keys
+func WithAPIKeys(apiKeys APIKeyVerifier) Option {
+	return func(s *jwtAuthService) {
+		s.apiKeys = apiKeys
+	}
+}
+
+// WithSessions records a session for every login so that users can see and
+// sign out their devices. Sessions require refresh tokens.
+func WithSessions(sessions repository.SessionRepository) Option {
+	return func(s *jwtAuthService) {
+		s.sessions = sessions
+	}
+}
+
+// WithAuditLog records token and session revocations in the audit log
+func WithAuditLog(audit service.AuditLogger) Option {
+	return func(s *jwtAuthService) {
+		s.audit = audit
+	}
+}
+
+// WithRequiredEmailVerification only issues and refreshes tokens of users who verified their email
+func WithRequiredEmailVerification(required bool) Option {
+	return func(s *jwtAuthService) {
+		s.requireVerifiedEmail = required
+	}
 }
 
 // NewJWTAuthService creates a new JWT auth service
-func NewJWTAuthService(secretKey string, tokenDuration time.Duration) AuthService {
-	return &jwtAuthService{
-		secretKey:     secretKey,
+func NewJWTAuthService(secretKey string, tokenDuration time.Duration, opts ...Option) AuthService {
+	s := &jwtAuthService{
+		keys:          NewHMACKeySet(secretKey),
 		tokenDuration: tokenDuration,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // GenerateToken generates a JWT token for a user
 func (s *jwtAuthService) GenerateToken(user *model.User) (string, error) {
+	return s.generateAccessToken(user, "")
+}
+
+// generateAccessToken generates a JWT token for a user bound to a session
+func (s *jwtAuthService) generateAccessToken(user *model.User, sessionID string) (string, error) {
 	// Set expiration time
 	expirationTime := time.Now().Add(s.tokenDuration)
 
+	// Every token gets a unique ID so that it can be revoked individually
+	tokenID, err := securetoken.New(16)
+	if err != nil {
+		return "", err
+	}
+
 	// Create claims
 	claims := &JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      string(user.GetRole()),
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -69,11 +273,8 @@ func (s *jwtAuthService) GenerateToken(user *model.User) (string, error) {
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token
-	tokenString, err := token.SignedString([]byte(s.secretKey))
+	// Create and sign token with the active key
+	tokenString, err := s.keys.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -81,19 +282,273 @@ func (s *jwtAuthService) GenerateToken(user *model.User) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token
-func (s *jwtAuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
+// GenerateTokenPair generates an access token and starts a new refresh token family
+func (s *jwtAuthService) GenerateTokenPair(ctx context.Context, user *model.User) (*TokenPair, error) {
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return nil, service.ErrEmailNotVerified
+	}
+
+	// Without a refresh token store only the access token is issued
+	if s.refreshTokens == nil {
+		accessToken, err := s.GenerateToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &TokenPair{AccessToken: accessToken, ExpiresIn: s.tokenDuration}, nil
+	}
+
+	familyID, err := securetoken.New(16)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateAccessToken(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.createSession(ctx, user.ID, familyID); err != nil {
+		return nil, err
+	}
+
+	pair := &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.tokenDuration,
+	}
+
+	return pair, nil
+}
+
+// RefreshTokens rotates a refresh token and issues a new token pair.
+// Presenting a refresh token that was already rotated revokes its whole family.
+func (s *jwtAuthService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, *model.User, error) {
+	if s.refreshTokens == nil {
+		return nil, nil, ErrRefreshNotEnabled
+	}
+	if refreshToken == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshTokens.GetByHash(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if stored.IsRevoked() || stored.IsExpired(now) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// A used token being presented again means it has leaked: kill the family
+	if stored.IsUsed() {
+		if err := s.refreshTokens.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	marked, err := s.refreshTokens.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !marked {
+		// Another request rotated this token first
+		if err := s.refreshTokens.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return nil, nil, service.ErrEmailNotVerified
+	}
+
+	accessToken, err := s.generateAccessToken(user, stored.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nextRefreshToken, err := s.issueRefreshToken(ctx, user.ID, stored.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The session lives as long as its newest refresh token
+	if s.sessions != nil {
+		ip := model.ClientInfoFromContext(ctx).IP
+		if err := s.sessions.Touch(ctx, stored.FamilyID, ip, now, now.Add(s.refreshDuration)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pair := &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: nextRefreshToken,
+		ExpiresIn:    s.tokenDuration,
+	}
+
+	return pair, user, nil
+}
+
+// issueRefreshToken creates and stores a new refresh token in the given family
+func (s *jwtAuthService) issueRefreshToken(ctx context.Context, userID, familyID string) (string, error) {
+	refreshToken, err := securetoken.New(securetoken.DefaultSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	stored := &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: securetoken.Hash(refreshToken),
+		ExpiresAt: now.Add(s.refreshDuration),
+		CreatedAt: now,
+	}
+
+	if err := s.refreshTokens.Create(ctx, stored); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// createSession records a new session for a refresh token family with the client of the request
+func (s *jwtAuthService) createSession(ctx context.Context, userID, familyID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+
+	client := model.ClientInfoFromContext(ctx)
+	now := time.Now()
+	session := &model.Session{
+		ID:         familyID,
+		UserID:     userID,
+		Device:     model.DeviceFromUserAgent(client.UserAgent),
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshDuration),
+	}
+
+	return s.sessions.Create(ctx, session)
+}
+
+// ValidateToken validates a JWT token and checks it against the revocation list
+func (s *jwtAuthService) ValidateToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued for other purposes do not grant access
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens of a signed out session are no longer accepted
+	if err := s.checkSession(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkSession rejects tokens whose session was revoked and records the activity on the session
+func (s *jwtAuthService) checkSession(ctx context.Context, claims *JWTClaims) error {
+	// Tokens issued before sessions were recorded have none
+	if s.sessions == nil || claims.SessionID == "" {
+		return nil
+	}
+
+	// Fail closed: a session that cannot be found was removed, for example when
+	// its user was erased, and one that cannot be loaded cannot be checked
+	session, err := s.sessions.GetByID(ctx, claims.SessionID)
+	if errors.Is(err, repo.ErrSessionNotFound) {
+		return ErrTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !session.RevokedAt.IsZero() {
+		return ErrTokenRevoked
+	}
+
+	// Avoid a write on every request
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		ip := model.ClientInfoFromContext(ctx).IP
+		if err := s.sessions.Touch(ctx, session.ID, ip, now, time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateMFAToken generates a short-lived token for completing a login with a second factor
+func (s *jwtAuthService) GenerateMFAToken(user *model.User) (string, time.Duration, error) {
+	tokenID, err := securetoken.New(16)
+	if err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Purpose: PurposeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   user.ID,
+		},
+	}
+
+	tokenString, err := s.keys.sign(claims)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return tokenString, mfaTokenDuration, nil
+}
+
+// ValidateMFAToken validates a token issued by GenerateMFAToken
+func (s *jwtAuthService) ValidateMFAToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != PurposeMFA {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// parseToken verifies a token of any purpose and checks it against the revocation list
+func (s *jwtAuthService) parseToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
 	// Parse token
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&JWTClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(s.secretKey), nil
-		},
+		// Select the key by kid and validate the signing method
+		s.keys.keyFunc,
 	)
 
 	if err != nil {
@@ -113,9 +568,201 @@ func (s *jwtAuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// Check the revocation list
+	if err := s.checkRevocation(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
+// checkRevocation rejects tokens that were revoked individually or through a user-wide revocation
+func (s *jwtAuthService) checkRevocation(ctx context.Context, claims *JWTClaims) error {
+	if s.revocations == nil {
+		return nil
+	}
+
+	if claims.ID != "" {
+		revoked, err := s.revocations.IsTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	revokedBefore, err := s.revocations.GetUserRevocation(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !revokedBefore.IsZero() && claims.IssuedAt != nil && !claims.IssuedAt.Time.After(revokedBefore) {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// RevokeToken revokes an access token and the refresh token family it belongs to
+func (s *jwtAuthService) RevokeToken(ctx context.Context, claims *JWTClaims) error {
+	now := time.Now()
+
+	if s.revocations != nil && claims.ID != "" {
+		revoked := &model.RevokedToken{
+			ID:        claims.ID,
+			UserID:    claims.UserID,
+			ExpiresAt: now.Add(s.tokenDuration),
+			RevokedAt: now,
+		}
+		if claims.ExpiresAt != nil {
+			revoked.ExpiresAt = claims.ExpiresAt.Time
+		}
+		if err := s.revocations.RevokeToken(ctx, revoked); err != nil {
+			return err
+		}
+	}
+
+	if s.refreshTokens != nil && claims.SessionID != "" {
+		if err := s.refreshTokens.RevokeFamily(ctx, claims.SessionID, now); err != nil {
+			return err
+		}
+	}
+
+	if s.sessions != nil && claims.SessionID != "" {
+		if _, err := s.sessions.Revoke(ctx, claims.SessionID, claims.UserID, now); err != nil {
+			return err
+		}
+	}
+
+	// Single-use tokens of other purposes are not access tokens
+	if claims.Purpose == "" {
+		s.record(ctx, model.AuditTokenRevoked, claims.UserID, map[string]string{"session_id": claims.SessionID})
+	}
+
+	return nil
+}
+
+// RevokePrincipalToken revokes the access token a principal authenticated with and its session.
+// The principal does not carry the token expiry, so the revocation is kept for the full token lifetime.
+func (s *jwtAuthService) RevokePrincipalToken(ctx context.Context, principal model.Principal) error {
+	claims := &JWTClaims{
+		UserID:    principal.UserID,
+		SessionID: principal.SessionID,
+	}
+	claims.ID = principal.TokenID
+
+	return s.RevokeToken(ctx, claims)
+}
+
+// RevokeAllForUser revokes every access and refresh token of a user
+func (s *jwtAuthService) RevokeAllForUser(ctx context.Context, userID string) error {
+	// Tokens only carry second precision, so everything issued up to and
+	// including the current second is revoked
+	now := time.Now().Truncate(time.Second)
+
+	if s.revocations != nil {
+		if err := s.revocations.RevokeUserTokens(ctx, userID, now); err != nil {
+			return err
+		}
+	}
+
+	if s.refreshTokens != nil {
+		if err := s.refreshTokens.RevokeByUser(ctx, userID, now); err != nil {
+			return err
+		}
+	}
+
+	if s.sessions != nil {
+		if err := s.sessions.RevokeByUser(ctx, userID, now); err != nil {
+			return err
+		}
+	}
+
+	s.record(ctx, model.AuditAllTokensRevoked, userID, nil)
+
+	return nil
+}
+
+// ListSessions returns the active login sessions of a user
+func (s *jwtAuthService) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	if s.sessions == nil {
+		return []*model.Session{}, nil
+	}
+
+	return s.sessions.ListActiveByUser(ctx, userID, time.Now())
+}
+
+// RevokeSession signs a user out of one session by revoking the session and its refresh tokens.
+// Access tokens of the session are rejected from then on.
+func (s *jwtAuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if s.sessions == nil {
+		return ErrSessionNotFound
+	}
+
+	now := time.Now()
+	revoked, err := s.sessions.Revoke(ctx, sessionID, userID, now)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	if s.refreshTokens != nil {
+		if err := s.refreshTokens.RevokeFamily(ctx, sessionID, now); err != nil {
+			return err
+		}
+	}
+
+	s.record(ctx, model.AuditSessionRevoked, userID, map[string]string{"session_id": sessionID})
+
+	return nil
+}
+
+// record records a revocation in the audit log. Users only ever revoke their own
+// tokens, so the user is both actor and target.
+func (s *jwtAuthService) record(ctx context.Context, action model.AuditAction, userID string, details map[string]string) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:   action,
+		ActorID:  userID,
+		TargetID: userID,
+		Details:  details,
+	})
+}
+
+// RevokeOtherSessions signs a user out of every session except the current one.
+// Without session tracking the sessions cannot be told apart and every token is revoked.
+func (s *jwtAuthService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID string) error {
+	if s.sessions == nil || currentSessionID == "" {
+		return s.RevokeAllForUser(ctx, userID)
+	}
+
+	sessions, err := s.sessions.ListActiveByUser(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.RevokeSession(ctx, userID, session.ID); err != nil && err != ErrSessionNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JWKS returns the public keys that can be used to verify tokens
+func (s *jwtAuthService) JWKS() *JWKS {
+	return s.keys.JWKS()
+}
+
 // ExtractTokenFromRequest extracts a token from an HTTP request
 func (s *jwtAuthService) ExtractTokenFromRequest(r *http.Request) (string, error) {
 	// Check Authorization header
@@ -136,4 +783,43 @@ func (s *jwtAuthService) ExtractTokenFromRequest(r *http.Request) (string, error
 	}
 
 	return "", ErrMissingToken
-}
\ No newline at end of file
+}
+
+// ValidateAPIKey checks an API key and returns claims for its owner and scopes
+func (s *jwtAuthService) ValidateAPIKey(ctx context.Context, key string) (*JWTClaims, error) {
+	if s.apiKeys == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, user, err := s.apiKeys.Verify(ctx, key)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	claims := &JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     string(user.GetRole()),
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: user.ID,
+		},
+	}
+
+	return claims, nil
+}
+
+// Authenticate authenticates a request with an API key header or a bearer token
+func (s *jwtAuthService) Authenticate(r *http.Request) (*JWTClaims, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return s.ValidateAPIKey(r.Context(), key)
+	}
+
+	tokenString, err := s.ExtractTokenFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ValidateToken(r.Context(), tokenString)
+}
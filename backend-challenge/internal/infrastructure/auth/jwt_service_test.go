@@ -1,11 +1,23 @@
 package auth
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+	"backend-challenge/internal/domain/service"
+	repo "backend-challenge/internal/infrastructure/repository"
 )
 
 func TestJWTAuthService(t *testing.T) {
@@ -33,7 +45,7 @@ func TestJWTAuthService(t *testing.T) {
 	}
 
 	// Test ValidateToken
-	claims, err := authService.ValidateToken(token)
+	claims, err := authService.ValidateToken(context.Background(), token)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -45,7 +57,7 @@ func TestJWTAuthService(t *testing.T) {
 	}
 
 	// Test invalid token
-	_, err = authService.ValidateToken("invalid-token")
+	_, err = authService.ValidateToken(context.Background(), "invalid-token")
 	if err == nil {
 		t.Errorf("Expected error for invalid token")
 	}
@@ -93,4 +105,546 @@ func TestExtractTokenFromRequest(t *testing.T) {
 	if err != ErrInvalidToken {
 		t.Errorf("Expected error %v, got %v", ErrInvalidToken, err)
 	}
-}
\ No newline at end of file
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+
+	// Create auth service with in-memory stores
+	users := repo.NewMockRepository()
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		15*time.Minute,
+		WithRefreshTokens(repo.NewMockRefreshTokenRepository(), users, time.Hour),
+	)
+
+	user := &model.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test GenerateTokenPair
+	pair, err := authService.GenerateTokenPair(ctx, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatalf("Expected access and refresh tokens")
+	}
+
+	// Test rotation
+	rotated, refreshedUser, err := authService.RefreshTokens(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if refreshedUser.ID != user.ID {
+		t.Errorf("Expected user ID %s, got %s", user.ID, refreshedUser.ID)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Errorf("Expected refresh token to be rotated")
+	}
+
+	// Test reuse of the rotated token
+	_, _, err = authService.RefreshTokens(ctx, pair.RefreshToken)
+	if err != ErrRefreshTokenReused {
+		t.Errorf("Expected error %v, got %v", ErrRefreshTokenReused, err)
+	}
+
+	// The newest token of the family must be revoked as well
+	_, _, err = authService.RefreshTokens(ctx, rotated.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+
+	// Test unknown token
+	_, _, err = authService.RefreshTokens(ctx, "unknown-token")
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+}
+
+func TestTokenRevocation(t *testing.T) {
+	ctx := context.Background()
+
+	// Create auth service with in-memory stores
+	users := repo.NewMockRepository()
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		15*time.Minute,
+		WithRefreshTokens(repo.NewMockRefreshTokenRepository(), users, time.Hour),
+		WithRevocationList(repo.NewMockTokenRevocationRepository()),
+	)
+
+	user := &model.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test RevokeToken
+	pair, err := authService.GenerateTokenPair(ctx, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	claims, err := authService.ValidateToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if claims.ID == "" {
+		t.Errorf("Expected token to carry a jti claim")
+	}
+	if err := authService.RevokeToken(ctx, claims); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = authService.ValidateToken(ctx, pair.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+	_, _, err = authService.RefreshTokens(ctx, pair.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+
+	// Test RevokePrincipalToken
+	pair, err = authService.GenerateTokenPair(ctx, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	claims, err = authService.ValidateToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	principal := claims.Principal()
+	if principal.TokenID != claims.ID || principal.SessionID != claims.SessionID {
+		t.Errorf("Expected principal to carry token %s and session %s, got %s and %s", claims.ID, claims.SessionID, principal.TokenID, principal.SessionID)
+	}
+	if err := authService.RevokePrincipalToken(ctx, principal); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = authService.ValidateToken(ctx, pair.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+	_, _, err = authService.RefreshTokens(ctx, pair.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+
+	// Test RevokeAllForUser
+	pair, err = authService.GenerateTokenPair(ctx, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := authService.RevokeAllForUser(ctx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = authService.ValidateToken(ctx, pair.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+	_, _, err = authService.RefreshTokens(ctx, pair.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+}
+
+func TestRequiredEmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	// Create auth service that requires a verified email
+	users := repo.NewMockRepository()
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		15*time.Minute,
+		WithRefreshTokens(repo.NewMockRefreshTokenRepository(), users, time.Hour),
+		WithRequiredEmailVerification(true),
+	)
+
+	user := &model.User{Name: "Test User", Email: "test@example.com", Password: "hashedpassword"}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: unverified users get no tokens
+	if _, err := authService.GenerateTokenPair(ctx, user); err != service.ErrEmailNotVerified {
+		t.Errorf("Expected error %v, got %v", service.ErrEmailNotVerified, err)
+	}
+
+	// Test case: refresh tokens of users who are no longer verified are rejected
+	user.EmailVerified = true
+	pair, err := authService.GenerateTokenPair(ctx, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	user.EmailVerified = false
+	if err := users.Update(ctx, user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := authService.RefreshTokens(ctx, pair.RefreshToken); err != service.ErrEmailNotVerified {
+		t.Errorf("Expected error %v, got %v", service.ErrEmailNotVerified, err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	// Create auth service with in-memory stores
+	users := repo.NewMockRepository()
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		15*time.Minute,
+		WithRefreshTokens(repo.NewMockRefreshTokenRepository(), users, time.Hour),
+		WithSessions(repo.NewMockSessionRepository()),
+	)
+
+	user := &model.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test sessions record the client of the login
+	laptop := model.WithClientInfo(context.Background(), model.ClientInfo{
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	})
+	phone := model.WithClientInfo(context.Background(), model.ClientInfo{
+		IP:        "198.51.100.1",
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	})
+
+	laptopPair, err := authService.GenerateTokenPair(laptop, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	phonePair, err := authService.GenerateTokenPair(phone, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sessions, err := authService.ListSessions(laptop, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	devices := map[string]string{}
+	for _, session := range sessions {
+		devices[session.IP] = session.Device
+	}
+	if devices["203.0.113.7"] != "Chrome on macOS" {
+		t.Errorf("Expected laptop device %q, got %q", "Chrome on macOS", devices["203.0.113.7"])
+	}
+	if devices["198.51.100.1"] != "Safari on iOS" {
+		t.Errorf("Expected phone device %q, got %q", "Safari on iOS", devices["198.51.100.1"])
+	}
+
+	// Test revoking a session of another user fails
+	phoneClaims, err := authService.ValidateToken(phone, phonePair.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := authService.RevokeSession(laptop, "other-user", phoneClaims.SessionID); err != ErrSessionNotFound {
+		t.Errorf("Expected error %v, got %v", ErrSessionNotFound, err)
+	}
+
+	// Test signing out the phone from the laptop
+	if err := authService.RevokeSession(laptop, user.ID, phoneClaims.SessionID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = authService.ValidateToken(phone, phonePair.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+	_, _, err = authService.RefreshTokens(phone, phonePair.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefreshToken, err)
+	}
+	if err := authService.RevokeSession(laptop, user.ID, phoneClaims.SessionID); err != ErrSessionNotFound {
+		t.Errorf("Expected error %v, got %v", ErrSessionNotFound, err)
+	}
+
+	// Test the laptop stays signed in
+	if _, err := authService.ValidateToken(laptop, laptopPair.AccessToken); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	sessions, err = authService.ListSessions(laptop, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].IP != "203.0.113.7" {
+		t.Errorf("Expected only the laptop session, got %+v", sessions)
+	}
+
+	// Test signing out every other session keeps the current one
+	tabletPair, err := authService.GenerateTokenPair(phone, user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	laptopClaims, err := authService.ValidateToken(laptop, laptopPair.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := authService.RevokeOtherSessions(laptop, user.ID, laptopClaims.SessionID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := authService.ValidateToken(laptop, laptopPair.AccessToken); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	_, err = authService.ValidateToken(phone, tabletPair.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+}
+
+// writeKeyFile writes a private key as PKCS#8 PEM to a temporary file
+func writeKeyFile(t *testing.T, name string, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return path
+}
+
+func TestAsymmetricKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rsaFile := writeKeyFile(t, "rsa.pem", rsaKey)
+	edFile := writeKeyFile(t, "ed25519.pem", edKey)
+
+	user := &model.User{
+		ID:    "user-123",
+		Email: "test@example.com",
+	}
+
+	// Sign with the RSA key
+	oldKeys, err := LoadKeySet(rsaFile, "key-1", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	oldService := NewJWTAuthService("", time.Hour, WithKeySet(oldKeys))
+	oldToken, err := oldService.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := oldService.ValidateToken(ctx, oldToken); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Rotate to the Ed25519 key, keeping the RSA key for verification
+	newKeys, err := LoadKeySet(edFile, "", []string{"key-1=" + rsaFile})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	newService := NewJWTAuthService("", time.Hour, WithKeySet(newKeys))
+	newToken, err := newService.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test tokens signed with both keys are accepted
+	if _, err := newService.ValidateToken(ctx, newToken); err != nil {
+		t.Errorf("Expected no error for new token, got %v", err)
+	}
+	if _, err := newService.ValidateToken(ctx, oldToken); err != nil {
+		t.Errorf("Expected no error for token signed with previous key, got %v", err)
+	}
+
+	// Test the old service does not know the new key
+	if _, err := oldService.ValidateToken(ctx, newToken); err != ErrInvalidToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidToken, err)
+	}
+
+	// Test HMAC tokens are rejected by an asymmetric key set
+	hmacToken, err := NewJWTAuthService("test-secret-key", time.Hour).GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := newService.ValidateToken(ctx, hmacToken); err != ErrInvalidToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidToken, err)
+	}
+
+	// Test JWKS publishes both public keys, active key first
+	jwks := newService.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Algorithm != "EdDSA" || jwks.Keys[0].KeyID == "" {
+		t.Errorf("Expected active EdDSA key with a kid, got %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Algorithm != "RS256" || jwks.Keys[1].KeyID != "key-1" {
+		t.Errorf("Expected previous RS256 key key-1, got %+v", jwks.Keys[1])
+	}
+
+	// Test shared secrets are never published
+	if keys := NewJWTAuthService("test-secret-key", time.Hour).JWKS().Keys; len(keys) != 0 {
+		t.Errorf("Expected no published keys for HMAC, got %d", len(keys))
+	}
+}
+
+func TestMFAToken(t *testing.T) {
+	ctx := context.Background()
+	authService := NewJWTAuthService("test-secret-key", time.Hour)
+	user := &model.User{ID: "user-123", Email: "test@example.com"}
+
+	// Test MFA tokens are not access tokens
+	mfaToken, expiresIn, err := authService.GenerateMFAToken(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expiresIn <= 0 || expiresIn > 10*time.Minute {
+		t.Errorf("Expected a short-lived token, got %v", expiresIn)
+	}
+	if _, err := authService.ValidateToken(ctx, mfaToken); err != ErrInvalidToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidToken, err)
+	}
+	claims, err := authService.ValidateMFAToken(ctx, mfaToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if claims.UserID != user.ID {
+		t.Errorf("Expected user ID %s, got %s", user.ID, claims.UserID)
+	}
+
+	// Test access tokens are not MFA tokens
+	accessToken, err := authService.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := authService.ValidateMFAToken(ctx, accessToken); err != ErrInvalidToken {
+		t.Errorf("Expected error %v, got %v", ErrInvalidToken, err)
+	}
+}
+
+// failingSessionRepository fails to load sessions while err is set
+type failingSessionRepository struct {
+	repository.SessionRepository
+	err error
+}
+
+func (r *failingSessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.SessionRepository.GetByID(ctx, id)
+}
+
+func TestSessionCheckFailsClosed(t *testing.T) {
+	// Create auth service with a session store that can fail
+	users := repo.NewMockRepository()
+	sessions := &failingSessionRepository{SessionRepository: repo.NewMockSessionRepository()}
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		15*time.Minute,
+		WithRefreshTokens(repo.NewMockRefreshTokenRepository(), users, time.Hour),
+		WithSessions(sessions),
+	)
+
+	user := &model.User{Name: "Test User", Email: "test@example.com", Password: "hashedpassword"}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pair, err := authService.GenerateTokenPair(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := authService.ValidateToken(context.Background(), pair.AccessToken); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: tokens are rejected while the session cannot be loaded
+	sessions.err = errors.New("connection refused")
+	if _, err := authService.ValidateToken(context.Background(), pair.AccessToken); err == nil {
+		t.Errorf("Expected error when the session cannot be loaded")
+	}
+	sessions.err = nil
+
+	// Test case: tokens of a removed session are rejected
+	if err := sessions.DeleteByUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := authService.ValidateToken(context.Background(), pair.AccessToken); err != ErrTokenRevoked {
+		t.Errorf("Expected error %v, got %v", ErrTokenRevoked, err)
+	}
+}
+
+// stubAPIKeyVerifier accepts a single API key
+type stubAPIKeyVerifier struct {
+	key  string
+	user *model.User
+}
+
+func (v *stubAPIKeyVerifier) Verify(ctx context.Context, key string) (*model.APIKey, *model.User, error) {
+	if key != v.key {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	return &model.APIKey{ID: "key-1", UserID: v.user.ID, Scopes: []string{model.ScopeTodosRead}}, v.user, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	user := &model.User{ID: "user-123", Email: "test@example.com"}
+	authService := NewJWTAuthService(
+		"test-secret-key",
+		time.Hour,
+		WithAPIKeys(&stubAPIKeyVerifier{key: "bck_valid", user: user}),
+	)
+
+	// Test API key header
+	req, _ := http.NewRequest("GET", "/api/todos", nil)
+	req.Header.Set(APIKeyHeader, "bck_valid")
+	claims, err := authService.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if claims.UserID != user.ID || claims.APIKeyID != "key-1" {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if !claims.HasScope(model.ScopeTodosRead) || claims.HasScope(model.ScopeTodosWrite) {
+		t.Errorf("Expected API key scopes to be enforced")
+	}
+
+	// Test invalid API key
+	req.Header.Set(APIKeyHeader, "bck_invalid")
+	if _, err := authService.Authenticate(req); err != ErrInvalidAPIKey {
+		t.Errorf("Expected error %v, got %v", ErrInvalidAPIKey, err)
+	}
+
+	// Test bearer tokens grant every scope
+	token, _ := authService.GenerateToken(user)
+	req, _ = http.NewRequest("GET", "/api/todos", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	claims, err = authService.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !claims.HasScope(model.ScopeUsersWrite) {
+		t.Errorf("Expected bearer token to grant every scope")
+	}
+}
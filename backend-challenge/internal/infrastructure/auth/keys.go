@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key errors
+var (
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	ErrUnknownKeyID   = errors.New("unknown key ID")
+)
+
+// SigningKey is a key used to sign or verify tokens
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signKey is the private key or HMAC secret, nil for verification-only keys
+	signKey interface{}
+	// verifyKey is the public key or HMAC secret
+	verifyKey interface{}
+}
+
+// KeySet holds the key used to sign new tokens and every key that is still
+// accepted when validating tokens, so that keys can be rotated without
+// invalidating tokens signed with previous keys
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JWK represents a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet creates a key set that signs tokens with a shared HS256 secret
+func NewHMACKeySet(secretKey string) *KeySet {
+	key := &SigningKey{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secretKey),
+		verifyKey: []byte(secretKey),
+	}
+
+	return &KeySet{
+		active: key,
+		keys:   map[string]*SigningKey{},
+	}
+}
+
+// NewKeySet creates a key set that signs tokens with the active private key and
+// also accepts tokens signed with the given previous public or private keys.
+// Keys without an ID get their RFC 7638 thumbprint as ID.
+func NewKeySet(active *SigningKey, previous ...*SigningKey) (*KeySet, error) {
+	if active == nil || active.signKey == nil {
+		return nil, errors.New("active key must be a private key")
+	}
+
+	ks := &KeySet{
+		active: active,
+		keys:   make(map[string]*SigningKey),
+	}
+
+	for _, key := range append([]*SigningKey{active}, previous...) {
+		if key.ID == "" {
+			thumbprint, err := key.thumbprint()
+			if err != nil {
+				return nil, err
+			}
+			key.ID = thumbprint
+		}
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	return ks, nil
+}
+
+// LoadKeySet loads the active private key and the previous keys from PEM files.
+// Previous keys may be given as "kid=path" to keep the ID they were published with.
+func LoadKeySet(privateKeyFile, keyID string, previousKeyFiles []string) (*KeySet, error) {
+	active, err := LoadSigningKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	active.ID = keyID
+
+	previous := make([]*SigningKey, 0, len(previousKeyFiles))
+	for _, entry := range previousKeyFiles {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path := "", entry
+		if i := strings.Index(entry, "="); i > 0 {
+			kid, path = entry[:i], entry[i+1:]
+		}
+
+		key, err := LoadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		key.ID = kid
+		previous = append(previous, key.verificationOnly())
+	}
+
+	return NewKeySet(active, previous...)
+}
+
+// LoadSigningKey reads an RSA or Ed25519 key from a PEM file.
+// Private keys can sign, public keys can only verify.
+func LoadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// ParseSigningKey parses a PEM encoded RSA or Ed25519 key
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigningKey(parsed)
+}
+
+// newSigningKey wraps a parsed key with its signing method
+func newSigningKey(parsed interface{}) (*SigningKey, error) {
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{Method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{Method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+}
+
+// verificationOnly returns a copy of the key without its private part
+func (k *SigningKey) verificationOnly() *SigningKey {
+	return &SigningKey{ID: k.ID, Method: k.Method, verifyKey: k.verifyKey}
+}
+
+// isHMAC reports whether the key is a shared secret
+func (k *SigningKey) isHMAC() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// jwk returns the public part of the key in JWK format
+func (k *SigningKey) jwk() (JWK, error) {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			KeyID:     k.ID,
+			Algorithm: k.Method.Alg(),
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			Use:       "sig",
+			KeyID:     k.ID,
+			Algorithm: k.Method.Alg(),
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of the key
+func (k *SigningKey) thumbprint() (string, error) {
+	jwk, err := k.jwk()
+	if err != nil {
+		return "", err
+	}
+
+	// Only the required members, in lexicographic order
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// sign signs the claims with the active key, setting the kid header
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	if ks.active.ID != "" {
+		token.Header["kid"] = ks.active.ID
+	}
+
+	return token.SignedString(ks.active.signKey)
+}
+
+// keyFunc selects the verification key for a token by its kid header
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := ks.active
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		found, exists := ks.keys[kid]
+		if !exists {
+			return nil, ErrUnknownKeyID
+		}
+		key = found
+	} else if !ks.active.isHMAC() {
+		// Asymmetric tokens are always issued with a kid
+		return nil, ErrUnknownKeyID
+	}
+
+	// The token must use the algorithm of the selected key
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys of the key set. Shared secrets are never published.
+func (ks *KeySet) JWKS() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+
+	// Publish the active key first
+	ordered := []*SigningKey{ks.active}
+	for id, key := range ks.keys {
+		if id != ks.active.ID {
+			ordered = append(ordered, key)
+		}
+	}
+
+	for _, key := range ordered {
+		if key.isHMAC() {
+			continue
+		}
+		jwk, err := key.jwk()
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
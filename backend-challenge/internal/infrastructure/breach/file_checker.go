@@ -0,0 +1,79 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"backend-challenge/internal/domain/service"
+)
+
+// prefixLength is the number of hex characters of the SHA-1 hash used to name range files
+const prefixLength = 5
+
+// fileChecker implements the BreachedPasswordChecker interface with local range files
+type fileChecker struct {
+	dir string
+}
+
+// NewFileChecker creates a breached password checker reading k-anonymity range files
+// from a directory, as published by Have I Been Pwned. Each file is named after the
+// first five hex characters of the SHA-1 hash (optionally with a .txt extension) and
+// lists the remaining 35 characters of every leaked hash as "SUFFIX:COUNT" lines.
+// Passwords are never sent anywhere, so the check works offline.
+func NewFileChecker(dir string) (service.BreachedPasswordChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password path %s is not a directory", dir)
+	}
+
+	return &fileChecker{dir: dir}, nil
+}
+
+// IsBreached reports whether the password appears in the range file of its hash prefix
+func (c *fileChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := c.openRange(prefix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The list may only cover part of the hash space
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		entry, count, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(entry, suffix) {
+			continue
+		}
+
+		// Padding entries added to hide the size of a range have a count of zero
+		return strings.TrimSpace(count) != "0", nil
+	}
+
+	return false, scanner.Err()
+}
+
+// openRange opens the range file of a hash prefix
+func (c *fileChecker) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(c.dir, prefix))
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(c.dir, prefix+".txt"))
+	}
+	return file, err
+}
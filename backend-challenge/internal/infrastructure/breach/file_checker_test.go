@@ -0,0 +1,48 @@
+package breach
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileChecker(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	ranges := "003D68EB55068C33ACE09247EE4C639306B:3\r\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD9:0\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(ranges), 0o600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	checker, err := NewFileChecker(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: listed password
+	breached, err := checker.IsBreached(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !breached {
+		t.Errorf("Expected password to be breached")
+	}
+
+	// Test case: prefix without a range file
+	breached, err = checker.IsBreached(context.Background(), "a much better passphrase")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if breached {
+		t.Errorf("Expected password not to be breached")
+	}
+
+	// Test case: missing directory
+	if _, err := NewFileChecker(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for a missing directory")
+	}
+}
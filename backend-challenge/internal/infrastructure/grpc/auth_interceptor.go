@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/infrastructure/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods lists the gRPC methods that can be called without a token
+var publicMethods = map[string]bool{
+	"/user.UserService/CreateUser": true,
+	"/user.UserService/Login":      true,
+}
+
+// UnaryAuthInterceptor validates the bearer token in the request metadata,
+// including the revocation check, for every method that is not public, and
+// stores the authenticated Principal in the context
+func UnaryAuthInterceptor(authService auth.AuthService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		// API keys are accepted in the x-api-key metadata
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+			claims, err := authService.ValidateAPIKey(ctx, md.Get("x-api-key")[0])
+			if err != nil {
+				return nil, mapDomainErrorToGRPC(err)
+			}
+			return handler(model.WithPrincipal(ctx, claims.Principal()), req)
+		}
+
+		tokenString, err := tokenFromMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Validate token
+		claims, err := authService.ValidateToken(ctx, tokenString)
+		if err != nil {
+			return nil, mapDomainErrorToGRPC(err)
+		}
+
+		// Store the authenticated caller for the method handlers
+		return handler(model.WithPrincipal(ctx, claims.Principal()), req)
+	}
+}
+
+// tokenFromMetadata extracts the bearer token from the authorization metadata
+func tokenFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "Missing metadata")
+	}
+
+	// Get authorization token
+	authHeader := md.Get("authorization")
+	if len(authHeader) == 0 {
+		return "", status.Error(codes.Unauthenticated, "Missing authorization metadata")
+	}
+
+	// Remove "Bearer " prefix if present
+	return strings.TrimPrefix(authHeader[0], "Bearer "), nil
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
 	"backend-challenge/internal/domain/model"
 	"backend-challenge/internal/domain/service"
@@ -114,16 +115,28 @@ func mapUserToProto(user *model.User) *User {
 
 // mapDomainErrorToGRPC maps domain errors to gRPC status errors
 func mapDomainErrorToGRPC(err error) error {
+	// Lockout errors carry the retry delay and are matched by their sentinel
+	if errors.Is(err, service.ErrTooManyLoginAttempts) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	// Password policy errors list the broken rules and are matched by their sentinel
+	if errors.Is(err, service.ErrWeakPassword) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	switch err {
 	case service.ErrUserNotFound:
 		return status.Error(codes.NotFound, err.Error())
 	case service.ErrEmailExists:
 		return status.Error(codes.AlreadyExists, err.Error())
-	case service.ErrInvalidID:
+	case service.ErrInvalidID, service.ErrInvalidRole, service.ErrWeakPassword, service.ErrInvalidResetToken, service.ErrInvalidVerificationToken:
 		return status.Error(codes.InvalidArgument, err.Error())
-	case service.ErrInvalidPassword:
+	case service.ErrForbidden, service.ErrEmailNotVerified:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case service.ErrInvalidPassword, service.ErrInvalidCredentials, service.ErrInvalidMFACode:
 		return status.Error(codes.Unauthenticated, err.Error())
-	case auth.ErrMissingToken, auth.ErrInvalidToken, auth.ErrTokenExpired:
+	case auth.ErrMissingToken, auth.ErrInvalidToken, auth.ErrTokenExpired, auth.ErrInvalidSignature, auth.ErrTokenRevoked, auth.ErrInvalidAPIKey:
 		return status.Error(codes.Unauthenticated, err.Error())
 	default:
 		return status.Error(codes.Internal, "Internal server error")
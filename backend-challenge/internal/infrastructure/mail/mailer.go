@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend-challenge/internal/domain/service"
+)
+
+// logMailer implements the Mailer interface by writing messages to the log
+type logMailer struct{}
+
+// NewLogMailer creates a mailer that logs messages instead of sending them.
+// Useful for development without an SMTP server.
+func NewLogMailer() service.Mailer {
+	return &logMailer{}
+}
+
+// Send writes the message to the log
+func (m *logMailer) Send(ctx context.Context, msg *service.MailMessage) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// fileMailer implements the Mailer interface by writing messages to files
+type fileMailer struct {
+	dir string
+	seq uint64
+}
+
+// NewFileMailer creates a mailer that writes each message as an .eml file to a directory
+func NewFileMailer(dir string) (service.Mailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	return &fileMailer{dir: dir}, nil
+}
+
+// Send writes the message to a new file in the mail directory
+func (m *fileMailer) Send(ctx context.Context, msg *service.MailMessage) error {
+	now := time.Now()
+	seq := atomic.AddUint64(&m.seq, 1)
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), seq)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	// Messages contain secrets, keep them readable by the owner only
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(b.String()), 0o600)
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/service"
+	"backend-challenge/internal/infrastructure/auth"
+)
+
+// authConfig holds the requirements of an auth middleware
+type authConfig struct {
+	allowAPIKeys bool
+	readScope    string
+	writeScope   string
+	role         model.Role
+}
+
+// AuthOption configures the auth middleware
+type AuthOption func(*authConfig)
+
+// AllowAPIKeys accepts API keys as well as bearer tokens. Keys need readScope for
+// GET requests and writeScope for everything else.
+func AllowAPIKeys(readScope, writeScope string) AuthOption {
+	return func(c *authConfig) {
+		c.allowAPIKeys = true
+		c.readScope = readScope
+		c.writeScope = writeScope
+	}
+}
+
+// RequireRole only lets principals with the role through
+func RequireRole(role model.Role) AuthOption {
+	return func(c *authConfig) {
+		c.role = role
+	}
+}
+
+// AuthMiddleware verifies the bearer token of a request, or its API key when allowed,
+// and stores the authenticated Principal in the request context. Handlers read the
+// caller from the context only, so identity headers sent by clients have no effect.
+func AuthMiddleware(authService auth.AuthService, opts ...AuthOption) func(http.Handler) http.Handler {
+	config := &authConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticate(authService, r, config.allowAPIKeys)
+			if err != nil {
+				writeError(w, err, http.StatusUnauthorized)
+				return
+			}
+			principal := claims.Principal()
+
+			// Check API key scopes
+			if config.allowAPIKeys && !principal.HasScope(requiredScope(r, config.readScope, config.writeScope)) {
+				writeError(w, service.ErrInsufficientScope, http.StatusForbidden)
+				return
+			}
+
+			// Check the role
+			if config.role != "" && principal.Role != config.role {
+				writeError(w, service.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(model.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// authenticate validates the API key or bearer token of a request. Without
+// allowAPIKeys the API key header is ignored and a bearer token is required.
+func authenticate(authService auth.AuthService, r *http.Request, allowAPIKeys bool) (*auth.JWTClaims, error) {
+	if allowAPIKeys {
+		return authService.Authenticate(r)
+	}
+
+	tokenString, err := authService.ExtractTokenFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return authService.ValidateToken(r.Context(), tokenString)
+}
+
+// requiredScope returns the scope an API key needs for the request method
+func requiredScope(r *http.Request, readScope, writeScope string) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return readScope
+	}
+	return writeScope
+}
+
+// PrincipalFromRequest returns the caller stored by AuthMiddleware, or an empty
+// Principal for requests that were not authenticated
+func PrincipalFromRequest(r *http.Request) model.Principal {
+	principal, _ := model.PrincipalFromContext(r.Context())
+	return principal
+}
+
+// writeError writes an error response in the format used by the handlers
+func writeError(w http.ResponseWriter, err error, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
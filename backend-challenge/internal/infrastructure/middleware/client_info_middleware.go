@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"backend-challenge/internal/domain/model"
+)
+
+// ClientInfoMiddleware stores the client IP and user agent in the request context.
+// X-Forwarded-For is only honoured when trustProxy is set, since clients can forge it.
+func ClientInfoMiddleware(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := model.ClientInfo{
+				IP:        clientIP(r, trustProxy),
+				UserAgent: r.UserAgent(),
+			}
+
+			next.ServeHTTP(w, r.WithContext(model.WithClientInfo(r.Context(), info)))
+		})
+	}
+}
+
+// clientIP returns the IP address of the client that sent the request
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		// The first address is the original client
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if ip := strings.TrimSpace(strings.Split(forwarded, ",")[0]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fileAuditRepository implements the AuditRepository interface with an append-only
+// file holding one JSON event per line
+type fileAuditRepository struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAuditRepository creates an audit repository writing to a file. It is used
+// when no database is available, so that the audit trail survives restarts.
+func NewFileAuditRepository(path string) (repository.AuditRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	// Check that the file can be written before the first event
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	file.Close()
+
+	return &fileAuditRepository{path: path}, nil
+}
+
+// Append records a new event as a line at the end of the file
+func (r *fileAuditRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	// Generate ID if not set
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// List returns the events matching the filter, newest first, with the total number of matches
+func (r *fileAuditRepository) List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	// Events are appended in order, so collect the matches and reverse them
+	var matches []*model.AuditEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event model.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Skip a line cut short by a crash
+			continue
+		}
+		if filter.Matches(&event) {
+			matches = append(matches, &event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(len(matches))
+	start := (filter.Page - 1) * filter.PageSize
+	events := []*model.AuditEvent{}
+	for i := len(matches) - 1 - start; i >= 0 && len(events) < filter.PageSize; i-- {
+		events = append(events, matches[i])
+	}
+
+	return events, total, nil
+}
+
+// AnonymizeUser replaces the ID of a user with a pseudonym in every event they took
+// part in, and removes the client details and email recorded with those events.
+// The file is rewritten to a temporary file which then replaces it.
+func (r *fileAuditRepository) AnonymizeUser(ctx context.Context, userID, pseudonym string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	temp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	writer := bufio.NewWriter(temp)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		var event model.AuditEvent
+		if err := json.Unmarshal(line, &event); err == nil && event.Anonymize(userID, pseudonym) {
+			if line, err = json.Marshal(&event); err != nil {
+				temp.Close()
+				return err
+			}
+		}
+
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), r.path)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"backend-challenge/pkg/cursor"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// afterCursorFilter matches the documents after a cursor when sorted by created_at and _id,
+// newest first if desc is set. It works for any collection with those two fields.
+func afterCursorFilter(c *cursor.Cursor, desc bool) bson.M {
+	op := "$gt"
+	if desc {
+		op = "$lt"
+	}
+	return bson.M{
+		"$or": []bson.M{
+			{"created_at": bson.M{op: c.CreatedAt}},
+			{"created_at": c.CreatedAt, "_id": bson.M{op: c.ID}},
+		},
+	}
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// API key errors
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// mongoAPIKeyRepository implements the APIKeyRepository interface
+type mongoAPIKeyRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoAPIKeyRepository creates a new MongoDB repository for API keys
+func NewMongoAPIKeyRepository(ctx context.Context, client *mongo.Client, dbName string) repository.APIKeyRepository {
+	repo := &mongoAPIKeyRepository{
+		client:     client,
+		database:   dbName,
+		collection: "api_keys",
+	}
+
+	// Create indexes for key lookups and listings
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for key lookups and listings
+func (r *mongoAPIKeyRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+
+	return err
+}
+
+// Create stores a new API key
+func (r *mongoAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Generate new ID if not set
+	if key.ID == "" {
+		key.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	_, err := collection.InsertOne(ctx, key)
+	return err
+}
+
+// GetByHash fetches an API key by the hash of its value
+func (r *mongoAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	var key model.APIKey
+	err := collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListByUser returns the API keys of a user, newest first
+func (r *mongoAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*model.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes an API key of a user
+func (r *mongoAPIKeyRepository) Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Scope by user so that nobody can revoke someone else's key
+	filter := bson.M{
+		"_id":        id,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UpdateLastUsed records when an API key was last used
+func (r *mongoAPIKeyRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+// DeleteByUser removes every API key of a user
+func (r *mongoAPIKeyRepository) DeleteByUser(ctx context.Context, userID string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// mockAPIKeyRepository implements the APIKeyRepository interface with in-memory storage
+type mockAPIKeyRepository struct {
+	keys map[string]*model.APIKey
+	mu   sync.RWMutex
+}
+
+// NewMockAPIKeyRepository creates a new in-memory API key repository
+func NewMockAPIKeyRepository() repository.APIKeyRepository {
+	return &mockAPIKeyRepository{
+		keys: make(map[string]*model.APIKey),
+	}
+}
+
+// Create stores a new API key
+func (r *mockAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Generate ID if not set
+	if key.ID == "" {
+		key.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	r.keys[key.ID] = key
+	return nil
+}
+
+// GetByHash fetches an API key by the hash of its value
+func (r *mockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// ListByUser returns the API keys of a user, newest first
+func (r *mockAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []*model.APIKey{}
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// Revoke revokes an API key of a user
+func (r *mockAPIKeyRepository) Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID || !key.RevokedAt.IsZero() {
+		return false, nil
+	}
+
+	key.RevokedAt = revokedAt
+	return true, nil
+}
+
+// UpdateLastUsed records when an API key was last used
+func (r *mockAPIKeyRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = usedAt
+	}
+	return nil
+}
+
+// DeleteByUser removes every API key of a user
+func (r *mockAPIKeyRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, key := range r.keys {
+		if key.UserID == userID {
+			delete(r.keys, id)
+		}
+	}
+	return nil
+}
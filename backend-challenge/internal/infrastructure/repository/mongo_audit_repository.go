@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoAuditRepository implements the AuditRepository interface
+type mongoAuditRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoAuditRepository creates a new MongoDB repository for the audit log
+func NewMongoAuditRepository(ctx context.Context, client *mongo.Client, dbName string) repository.AuditRepository {
+	repo := &mongoAuditRepository{
+		client:     client,
+		database:   dbName,
+		collection: "audit_events",
+	}
+
+	// Create indexes for the filters
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for the filters
+func (r *mongoAuditRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+
+	return err
+}
+
+// Append records a new event
+func (r *mongoAuditRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Generate new ID if not set
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	_, err := collection.InsertOne(ctx, event)
+	return err
+}
+
+// List returns the events matching the filter, newest first, with the total number of matches
+func (r *mongoAuditRepository) List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEvent, int64, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	query := bson.M{}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.ActorID != "" {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+	if filter.UserID != "" {
+		query["$or"] = []bson.M{
+			{"actor_id": filter.UserID},
+			{"target_id": filter.UserID},
+		}
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		createdAt := bson.M{}
+		if !filter.From.IsZero() {
+			createdAt["$gte"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			createdAt["$lt"] = filter.To
+		}
+		query["created_at"] = createdAt
+	}
+
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((filter.Page - 1) * filter.PageSize)).
+		SetLimit(int64(filter.PageSize))
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []*model.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// AnonymizeUser replaces the ID of a user with a pseudonym in every event they took
+// part in, and removes the client details and email recorded with those events
+func (r *mongoAuditRepository) AnonymizeUser(ctx context.Context, userID, pseudonym string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	unset := bson.M{"ip": "", "user_agent": ""}
+	for _, key := range model.AuditPersonalDetails {
+		unset["details."+key] = ""
+	}
+
+	// The actor and the target are replaced separately as either can be the user
+	for _, field := range []string{"actor_id", "target_id"} {
+		update := bson.M{
+			"$set":   bson.M{field: pseudonym},
+			"$unset": unset,
+		}
+		if _, err := collection.UpdateMany(ctx, bson.M{field: userID}, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoLoginAttemptRepository implements the LoginAttemptRepository interface
+type mongoLoginAttemptRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoLoginAttemptRepository creates a new MongoDB repository for failed login counters
+func NewMongoLoginAttemptRepository(ctx context.Context, client *mongo.Client, dbName string) repository.LoginAttemptRepository {
+	repo := &mongoLoginAttemptRepository{
+		client:     client,
+		database:   dbName,
+		collection: "login_attempts",
+	}
+
+	// Create index for expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create index for expiry
+func (r *mongoLoginAttemptRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Counters are dropped once they have expired
+	_, err := collection.Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	)
+
+	return err
+}
+
+// Get returns the failed login counter for a key
+func (r *mongoLoginAttemptRepository) Get(ctx context.Context, key string, now time.Time) (*model.LoginAttempts, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// The TTL monitor only runs periodically, so skip expired counters explicitly
+	filter := bson.M{
+		"_id":        key,
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var attempts model.LoginAttempts
+	err := collection.FindOne(ctx, filter).Decode(&attempts)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &attempts, nil
+}
+
+// RecordFailure atomically increments the counter for a key and returns it
+func (r *mongoLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now, expiresAt time.Time) (*model.LoginAttempts, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Drop a counter that expired but was not yet removed by the TTL monitor
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": key, "expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{
+			"last_failure_at": now,
+			"expires_at":      expiresAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var attempts model.LoginAttempts
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempts)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent upsert created the counter first, increment it instead
+		err = collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempts, nil
+}
+
+// Release atomically decrements the counter for a key
+func (r *mongoLoginAttemptRepository) Release(ctx context.Context, key string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"_id":      key,
+		"failures": bson.M{"$gt": 0},
+	}
+	_, err := collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"failures": -1}})
+	return err
+}
+
+// Reset clears the counter for a key
+func (r *mongoLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// mockLoginAttemptRepository implements the LoginAttemptRepository interface with in-memory storage
+type mockLoginAttemptRepository struct {
+	attempts map[string]*model.LoginAttempts
+	mu       sync.Mutex
+}
+
+// NewMockLoginAttemptRepository creates a new in-memory failed login counter repository
+func NewMockLoginAttemptRepository() repository.LoginAttemptRepository {
+	return &mockLoginAttemptRepository{
+		attempts: make(map[string]*model.LoginAttempts),
+	}
+}
+
+// Get returns the failed login counter for a key
+func (r *mockLoginAttemptRepository) Get(ctx context.Context, key string, now time.Time) (*model.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[key]
+	if !ok || !now.Before(attempts.ExpiresAt) {
+		return nil, nil
+	}
+
+	copied := *attempts
+	return &copied, nil
+}
+
+// RecordFailure atomically increments the counter for a key and returns it
+func (r *mockLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now, expiresAt time.Time) (*model.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[key]
+	if !ok || !now.Before(attempts.ExpiresAt) {
+		attempts = &model.LoginAttempts{Key: key}
+		r.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	attempts.ExpiresAt = expiresAt
+
+	copied := *attempts
+	return &copied, nil
+}
+
+// Release atomically decrements the counter for a key
+func (r *mockLoginAttemptRepository) Release(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempts, ok := r.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+	}
+	return nil
+}
+
+// Reset clears the counter for a key
+func (r *mockLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
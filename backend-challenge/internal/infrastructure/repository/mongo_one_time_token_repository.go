@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// One-time token errors
+var (
+	ErrOneTimeTokenNotFound = errors.New("one-time token not found")
+)
+
+// mongoOneTimeTokenRepository implements the OneTimeTokenRepository interface
+type mongoOneTimeTokenRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoOneTimeTokenRepository creates a new MongoDB repository for one-time tokens
+func NewMongoOneTimeTokenRepository(ctx context.Context, client *mongo.Client, dbName string) repository.OneTimeTokenRepository {
+	repo := &mongoOneTimeTokenRepository{
+		client:     client,
+		database:   dbName,
+		collection: "one_time_tokens",
+	}
+
+	// Create indexes for token lookups and expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for token lookups and expiry
+func (r *mongoOneTimeTokenRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			// Let MongoDB remove tokens once they have expired
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
+
+// Create stores a new one-time token
+func (r *mongoOneTimeTokenRepository) Create(ctx context.Context, token *model.OneTimeToken) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Generate new ID if not set
+	if token.ID == "" {
+		token.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// GetValid fetches an unused, unexpired token without consuming it
+func (r *mongoOneTimeTokenRepository) GetValid(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var token model.OneTimeToken
+	err := collection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrOneTimeTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Consume marks an unused, unexpired token as used and returns it
+func (r *mongoOneTimeTokenRepository) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Match and mark in a single operation so that a token can only be used once
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"used_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token model.OneTimeToken
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrOneTimeTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// DeleteByUser removes every token of a user with the given purpose
+func (r *mongoOneTimeTokenRepository) DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
+
+// mockOneTimeTokenRepository implements the OneTimeTokenRepository interface with in-memory storage
+type mockOneTimeTokenRepository struct {
+	tokens map[string]*model.OneTimeToken
+	mu     sync.Mutex
+}
+
+// NewMockOneTimeTokenRepository creates a new in-memory one-time token repository
+func NewMockOneTimeTokenRepository() repository.OneTimeTokenRepository {
+	return &mockOneTimeTokenRepository{
+		tokens: make(map[string]*model.OneTimeToken),
+	}
+}
+
+// Create stores a new one-time token
+func (r *mockOneTimeTokenRepository) Create(ctx context.Context, token *model.OneTimeToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Generate ID if not set
+	if token.ID == "" {
+		token.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	r.tokens[token.ID] = token
+	return nil
+}
+
+// GetValid fetches an unused, unexpired token without consuming it
+func (r *mockOneTimeTokenRepository) GetValid(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash != tokenHash || token.Purpose != purpose {
+			continue
+		}
+		if token.IsUsed() || token.IsExpired(now) {
+			return nil, ErrOneTimeTokenNotFound
+		}
+
+		copied := *token
+		return &copied, nil
+	}
+	return nil, ErrOneTimeTokenNotFound
+}
+
+// Consume marks an unused, unexpired token as used and returns it
+func (r *mockOneTimeTokenRepository) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash string, now time.Time) (*model.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash != tokenHash || token.Purpose != purpose {
+			continue
+		}
+		if token.IsUsed() || token.IsExpired(now) {
+			return nil, ErrOneTimeTokenNotFound
+		}
+
+		token.UsedAt = now
+		copied := *token
+		return &copied, nil
+	}
+	return nil, ErrOneTimeTokenNotFound
+}
+
+// DeleteByUser removes every token of a user with the given purpose
+func (r *mockOneTimeTokenRepository) DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Privacy job errors
+var (
+	ErrPrivacyJobNotFound = errors.New("privacy job not found")
+)
+
+// mongoPrivacyJobRepository implements the PrivacyJobRepository interface
+type mongoPrivacyJobRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoPrivacyJobRepository creates a new MongoDB repository for data export and erasure jobs
+func NewMongoPrivacyJobRepository(ctx context.Context, client *mongo.Client, dbName string) repository.PrivacyJobRepository {
+	repo := &mongoPrivacyJobRepository{
+		client:     client,
+		database:   dbName,
+		collection: "privacy_jobs",
+	}
+
+	// Create indexes for lookups, the job queue and expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for lookups, the job queue and expiry
+func (r *mongoPrivacyJobRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			// Let MongoDB remove export archives once they have expired
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
+
+// Create stores a new job
+func (r *mongoPrivacyJobRepository) Create(ctx context.Context, job *model.PrivacyJob) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Generate new ID if not set
+	if job.ID == "" {
+		job.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+
+	_, err := collection.InsertOne(ctx, job)
+	return err
+}
+
+// GetByID fetches a job by ID
+func (r *mongoPrivacyJobRepository) GetByID(ctx context.Context, id string) (*model.PrivacyJob, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	var job model.PrivacyJob
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPrivacyJobNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetLatest returns the most recent job of a type for a user, or nil if there is none
+func (r *mongoPrivacyJobRepository) GetLatest(ctx context.Context, userID string, jobType model.PrivacyJobType) (*model.PrivacyJob, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{"user_id": userID, "type": jobType}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var job model.PrivacyJob
+	err := collection.FindOne(ctx, filter, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ClaimNext marks the oldest pending job as running and returns it, or nil if there is none
+func (r *mongoPrivacyJobRepository) ClaimNext(ctx context.Context, now, staleBefore time.Time) (*model.PrivacyJob, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Claim atomically so that several instances can run jobs side by side
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": model.PrivacyJobPending},
+			{"status": model.PrivacyJobRunning, "started_at": bson.M{"$lt": staleBefore}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"status": model.PrivacyJobRunning, "started_at": now},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job model.PrivacyJob
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Update saves the status, result and timestamps of a job
+func (r *mongoPrivacyJobRepository) Update(ctx context.Context, job *model.PrivacyJob) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPrivacyJobNotFound
+	}
+
+	return nil
+}
+
+// DeleteExports removes every export job of a user, with their archives
+func (r *mongoPrivacyJobRepository) DeleteExports(ctx context.Context, userID string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID, "type": model.PrivacyJobExport})
+	return err
+}
+
+// mockPrivacyJobRepository implements the PrivacyJobRepository interface with in-memory storage
+type mockPrivacyJobRepository struct {
+	jobs map[string]*model.PrivacyJob
+	mu   sync.RWMutex
+}
+
+// NewMockPrivacyJobRepository creates a new in-memory privacy job repository
+func NewMockPrivacyJobRepository() repository.PrivacyJobRepository {
+	return &mockPrivacyJobRepository{
+		jobs: make(map[string]*model.PrivacyJob),
+	}
+}
+
+// Create stores a new job
+func (r *mockPrivacyJobRepository) Create(ctx context.Context, job *model.PrivacyJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job.ID == "" {
+		job.ID = primitive.NewObjectID().Hex()
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+
+	copied := *job
+	r.jobs[job.ID] = &copied
+	return nil
+}
+
+// GetByID fetches a job by ID
+func (r *mockPrivacyJobRepository) GetByID(ctx context.Context, id string) (*model.PrivacyJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrPrivacyJobNotFound
+	}
+
+	copied := *job
+	return &copied, nil
+}
+
+// GetLatest returns the most recent job of a type for a user, or nil if there is none
+func (r *mockPrivacyJobRepository) GetLatest(ctx context.Context, userID string, jobType model.PrivacyJobType) (*model.PrivacyJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *model.PrivacyJob
+	for _, job := range r.jobs {
+		if job.UserID != userID || job.Type != jobType {
+			continue
+		}
+		if latest == nil || job.CreatedAt.After(latest.CreatedAt) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	copied := *latest
+	return &copied, nil
+}
+
+// ClaimNext marks the oldest pending job as running and returns it, or nil if there is none
+func (r *mockPrivacyJobRepository) ClaimNext(ctx context.Context, now, staleBefore time.Time) (*model.PrivacyJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next *model.PrivacyJob
+	for _, job := range r.jobs {
+		claimable := job.Status == model.PrivacyJobPending ||
+			(job.Status == model.PrivacyJobRunning && job.StartedAt.Before(staleBefore))
+		if claimable && (next == nil || job.CreatedAt.Before(next.CreatedAt)) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	next.Status = model.PrivacyJobRunning
+	next.StartedAt = now
+
+	copied := *next
+	return &copied, nil
+}
+
+// Update saves the status, result and timestamps of a job
+func (r *mockPrivacyJobRepository) Update(ctx context.Context, job *model.PrivacyJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[job.ID]; !ok {
+		return ErrPrivacyJobNotFound
+	}
+
+	copied := *job
+	r.jobs[job.ID] = &copied
+	return nil
+}
+
+// DeleteExports removes every export job of a user, with their archives
+func (r *mockPrivacyJobRepository) DeleteExports(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, job := range r.jobs {
+		if job.UserID == userID && job.Type == model.PrivacyJobExport {
+			delete(r.jobs, id)
+		}
+	}
+	return nil
+}
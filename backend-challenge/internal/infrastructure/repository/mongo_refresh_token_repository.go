@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Refresh token errors
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+)
+
+// mongoRefreshTokenRepository implements the RefreshTokenRepository interface
+type mongoRefreshTokenRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoRefreshTokenRepository creates a new MongoDB repository for refresh tokens
+func NewMongoRefreshTokenRepository(ctx context.Context, client *mongo.Client, dbName string) repository.RefreshTokenRepository {
+	repo := &mongoRefreshTokenRepository{
+		client:     client,
+		database:   dbName,
+		collection: "refresh_tokens",
+	}
+
+	// Create indexes for token lookups and expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for token lookups and expiry
+func (r *mongoRefreshTokenRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			// Let MongoDB remove tokens once they have expired
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
+
+// Create stores a new refresh token
+func (r *mongoRefreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Generate new ID if not set
+	if token.ID == "" {
+		token.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// GetByHash fetches a refresh token by the hash of its value
+func (r *mongoRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	var token model.RefreshToken
+	err := collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed marks an unused refresh token as used
+func (r *mongoRefreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Only match tokens that have not been used yet so that two concurrent
+	// rotations of the same token cannot both succeed
+	filter := bson.M{
+		"_id":     id,
+		"used_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"used_at": usedAt},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// RevokeFamily revokes every refresh token belonging to a token family
+func (r *mongoRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"family_id":  familyID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// RevokeByUser revokes every refresh token belonging to a user
+func (r *mongoRefreshTokenRepository) RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// DeleteByUser removes every refresh token belonging to a user
+func (r *mongoRefreshTokenRepository) DeleteByUser(ctx context.Context, userID string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// mockRefreshTokenRepository implements the RefreshTokenRepository interface with in-memory storage
+type mockRefreshTokenRepository struct {
+	tokens map[string]*model.RefreshToken
+	mu     sync.RWMutex
+}
+
+// NewMockRefreshTokenRepository creates a new in-memory refresh token repository
+func NewMockRefreshTokenRepository() repository.RefreshTokenRepository {
+	return &mockRefreshTokenRepository{
+		tokens: make(map[string]*model.RefreshToken),
+	}
+}
+
+// Create stores a new refresh token
+func (r *mockRefreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Generate ID if not set
+	if token.ID == "" {
+		token.ID = primitive.NewObjectID().Hex()
+	}
+
+	// Set creation time if not set
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	// Store a copy so that callers cannot change the stored token
+	copied := *token
+	r.tokens[token.ID] = &copied
+	return nil
+}
+
+// GetByHash fetches a refresh token by the hash of its value
+func (r *mockRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrRefreshTokenNotFound
+}
+
+// MarkUsed marks an unused refresh token as used
+func (r *mockRefreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return false, ErrRefreshTokenNotFound
+	}
+	if token.IsUsed() {
+		return false, nil
+	}
+
+	token.UsedAt = usedAt
+	return true, nil
+}
+
+// RevokeFamily revokes every refresh token belonging to a token family
+func (r *mockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && !token.IsRevoked() {
+			token.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+// RevokeByUser revokes every refresh token belonging to a user
+func (r *mockRefreshTokenRepository) RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.UserID == userID && !token.IsRevoked() {
+			token.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+// DeleteByUser removes every refresh token belonging to a user
+func (r *mockRefreshTokenRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +20,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// duplicateKeyCode is the MongoDB error code for unique index violations
+const duplicateKeyCode = 11000
+
 // Common errors
 var (
 	ErrDuplicateEmail = errors.New("email already exists")
@@ -25,6 +30,23 @@ var (
 	ErrDatabase       = errors.New("database error")
 )
 
+// userDocument is a user as stored, with lowercased copies of the name and email
+// so that searches can use an index
+type userDocument struct {
+	model.User `bson:",inline"`
+	NameLower  string `bson:"name_lower"`
+	EmailLower string `bson:"email_lower"`
+}
+
+// newUserDocument returns the stored form of a user
+func newUserDocument(user *model.User) *userDocument {
+	return &userDocument{
+		User:       *user,
+		NameLower:  strings.ToLower(user.Name),
+		EmailLower: strings.ToLower(user.Email),
+	}
+}
+
 // MongoRepository implements the UserRepository interface
 type MongoRepository struct {
 	Client     *mongo.Client
@@ -83,6 +105,12 @@ func NewMongoRepository(ctx context.Context, uri, dbName string) (repository.Use
 		return nil, err
 	}
 
+	// Add the search fields to users stored before they existed
+	err = repo.backfillSearchFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return repo, nil
 }
 
@@ -126,13 +154,26 @@ func (r *mockRepository) Create(ctx context.Context, user *model.User) error {
 	return nil
 }
 
+// CreateMany adds users, skipping those whose email is taken
+func (r *mockRepository) CreateMany(ctx context.Context, users []*model.User) ([]int, error) {
+	var duplicates []int
+	for i, user := range users {
+		if err := r.Create(ctx, user); err == ErrDuplicateEmail {
+			duplicates = append(duplicates, i)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return duplicates, nil
+}
+
 // GetByID fetches a user by ID
 func (r *mockRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	user, ok := r.users[id]
-	if !ok {
+	if !ok || user.IsDeleted() {
 		return nil, ErrUserNotFound
 	}
 	return user, nil
@@ -144,7 +185,7 @@ func (r *mockRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	defer r.mu.RUnlock()
 
 	for _, user := range r.users {
-		if user.Email == email {
+		if user.Email == email && !user.IsDeleted() {
 			return user, nil
 		}
 	}
@@ -157,7 +198,7 @@ func (r *mockRepository) Update(ctx context.Context, user *model.User) error {
 	defer r.mu.Unlock()
 
 	// Check if user exists
-	if _, ok := r.users[user.ID]; !ok {
+	if existing, ok := r.users[user.ID]; !ok || existing.IsDeleted() {
 		return ErrUserNotFound
 	}
 
@@ -173,46 +214,121 @@ func (r *mockRepository) Update(ctx context.Context, user *model.User) error {
 	return nil
 }
 
-// Delete removes a user
-func (r *mockRepository) Delete(ctx context.Context, id string) error {
+// UpdateMFA replaces the two-factor state of a user if it still equals previous
+func (r *mockRepository) UpdateMFA(ctx context.Context, id string, previous, mfa model.MFA) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.users[id]; !ok {
+	user, ok := r.users[id]
+	if !ok || user.IsDeleted() || !user.MFA.Equal(previous) {
+		return false, nil
+	}
+
+	user.MFA = mfa
+	return true, nil
+}
+
+// Delete soft deletes a user
+func (r *mockRepository) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.IsDeleted() {
 		return ErrUserNotFound
 	}
 
+	user.DeletedAt = &deletedAt
+	return nil
+}
+
+// Erase permanently removes a user
+func (r *mockRepository) Erase(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return false, nil
+	}
+
 	delete(r.users, id)
+	return true, nil
+}
+
+// Restore undoes the soft deletion of a user
+func (r *mockRepository) Restore(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !user.IsDeleted() {
+		return ErrUserNotFound
+	}
+
+	user.DeletedAt = nil
 	return nil
 }
 
-// List returns all users with pagination
-func (r *mockRepository) List(ctx context.Context, page, pageSize int) ([]*model.User, error) {
+// ListDeleted returns the users soft deleted before the given time
+func (r *mockRepository) ListDeleted(ctx context.Context, before time.Time) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*model.User{}
+	for _, user := range r.users {
+		if user.IsDeleted() && user.DeletedAt.Before(before) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// List returns a page of the users matching the options with the total number of matches
+func (r *mockRepository) List(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var users []*model.User
 	for _, user := range r.users {
-		users = append(users, user)
+		if !user.IsDeleted() && opts.Matches(user) {
+			users = append(users, user)
+		}
+	}
+	// Cursor pages are not counted, like in MongoDB
+	var total int64
+	if opts.After == nil {
+		total = int64(len(users))
 	}
 
-	// Sort by created_at
+	// Sort by the requested field
 	sort.Slice(users, func(i, j int) bool {
-		return users[i].CreatedAt.After(users[j].CreatedAt)
+		return opts.Less(users[i], users[j])
 	})
 
-	// Paginate
-	start := (page - 1) * pageSize
+	// Paginate after the cursor or by offset
+	if opts.After != nil {
+		after := []*model.User{}
+		for _, user := range users {
+			if opts.Follows(user) {
+				after = append(after, user)
+			}
+		}
+		users = after
+	}
+	start := 0
+	if opts.After == nil {
+		start = (opts.Page - 1) * opts.PageSize
+	}
 	if start >= len(users) {
-		return []*model.User{}, nil
+		return []*model.User{}, total, nil
 	}
 
-	end := start + pageSize
+	end := start + opts.PageSize
 	if end > len(users) {
 		end = len(users)
 	}
 
-	return users[start:end], nil
+	return users[start:end], total, nil
 }
 
 // CountUsers returns the total number of users
@@ -220,7 +336,13 @@ func (r *mockRepository) CountUsers(ctx context.Context) (int64, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return int64(len(r.users)), nil
+	var count int64
+	for _, user := range r.users {
+		if !user.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
 }
 
 // Disconnect closes the connection
@@ -228,22 +350,48 @@ func (r *mockRepository) Disconnect(ctx context.Context) error {
 	return nil
 }
 
-// Create unique index for email
+// Create unique index for email and indexes for listing and purging users
 func (r *MongoRepository) createIndexes(ctx context.Context) error {
 	collection := r.Client.Database(r.database).Collection(r.collection)
 	
-	// Create unique index for email
-	_, err := collection.Indexes().CreateOne(
-		ctx,
-		mongo.IndexModel{
+	// Create unique index for email, indexes for sorting by name and creation time,
+	// for searching the lowercased name and email, and for purging deleted users. The email index covers
+	// deleted users too, so their email cannot be registered again until they are
+	// purged and restoring a user can never create a duplicate.
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
 			Keys:    bson.D{{Key: "email", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
-	)
+		{Keys: bson.D{{Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "name_lower", Value: 1}}},
+		{Keys: bson.D{{Key: "email_lower", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	})
 	
 	return err
 }
 
+// backfillSearchFields sets the lowercased name and email of users that lack them.
+// $toLower only lowercases ASCII letters; the fields are rewritten in full on the next update.
+func (r *MongoRepository) backfillSearchFields(ctx context.Context) error {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "name_lower", Value: bson.M{"$toLower": "$name"}},
+			{Key: "email_lower", Value: bson.M{"$toLower": "$email"}},
+		}}},
+	}
+	_, err := collection.UpdateMany(ctx, bson.M{"name_lower": bson.M{"$exists": false}}, update)
+
+	return err
+}
+
 // Create adds a new user to the database
 func (r *MongoRepository) Create(ctx context.Context, user *model.User) error {
 	collection := r.Client.Database(r.database).Collection(r.collection)
@@ -259,7 +407,7 @@ func (r *MongoRepository) Create(ctx context.Context, user *model.User) error {
 	}
 	
 	// Insert the document
-	_, err := collection.InsertOne(ctx, user)
+	_, err := collection.InsertOne(ctx, newUserDocument(user))
 	if mongo.IsDuplicateKeyError(err) {
 		return ErrDuplicateEmail
 	}
@@ -267,6 +415,45 @@ func (r *MongoRepository) Create(ctx context.Context, user *model.User) error {
 	return err
 }
 
+// CreateMany inserts users in one unordered batch so that a taken email only skips that user
+func (r *MongoRepository) CreateMany(ctx context.Context, users []*model.User) ([]int, error) {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	documents := make([]interface{}, len(users))
+	for i, user := range users {
+		// Generate new ID if not set
+		if user.ID == "" {
+			user.ID = primitive.NewObjectID().Hex()
+		}
+
+		// Set creation time if not set
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = time.Now()
+		}
+
+		documents[i] = newUserDocument(user)
+	}
+
+	_, err := collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return nil, nil
+	}
+
+	// Report duplicate emails, any other write error fails the batch
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return nil, err
+	}
+	var duplicates []int
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return nil, err
+		}
+		duplicates = append(duplicates, writeErr.Index)
+	}
+	return duplicates, nil
+}
+
 // GetByID fetches a user by ID
 func (r *MongoRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	collection := r.Client.Database(r.database).Collection(r.collection)
@@ -285,6 +472,7 @@ func (r *MongoRepository) GetByID(ctx context.Context, id string) (*model.User,
 		// Use string ID
 		filter = bson.M{"_id": id}
 	}
+	filter["deleted_at"] = nil // Skip soft deleted users
 
 	var user model.User
 	err := collection.FindOne(ctx, filter).Decode(&user)
@@ -303,7 +491,7 @@ func (r *MongoRepository) GetByEmail(ctx context.Context, email string) (*model.
 	collection := r.Client.Database(r.database).Collection(r.collection)
 	
 	var user model.User
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := collection.FindOne(ctx, bson.M{"email": email, "deleted_at": nil}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, ErrUserNotFound
@@ -332,11 +520,19 @@ func (r *MongoRepository) Update(ctx context.Context, user *model.User) error {
 		// Use string ID
 		filter = bson.M{"_id": user.ID}
 	}
+	filter["deleted_at"] = nil // Skip soft deleted users
 	
 	update := bson.M{
 		"$set": bson.M{
-			"name":  user.Name,
-			"email": user.Email,
+			"name":           user.Name,
+			"email":          user.Email,
+			"name_lower":     strings.ToLower(user.Name),
+			"email_lower":    strings.ToLower(user.Email),
+			"password":       user.Password,
+			"role":           user.GetRole(),
+			"email_verified": user.EmailVerified,
+			"mfa":            user.MFA,
+			"profile":        user.Profile,
 		},
 	}
 	
@@ -355,71 +551,200 @@ func (r *MongoRepository) Update(ctx context.Context, user *model.User) error {
 	return nil
 }
 
-// Delete removes a user from the database
-func (r *MongoRepository) Delete(ctx context.Context, id string) error {
+// UpdateMFA replaces the two-factor state of a user if it still equals previous
+func (r *MongoRepository) UpdateMFA(ctx context.Context, id string, previous, mfa model.MFA) (bool, error) {
 	collection := r.Client.Database(r.database).Collection(r.collection)
-	
-	// Check if ID is a valid ObjectID
-	var filter bson.M
-	if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
-		// Try to delete by ObjectID or string ID
-		filter = bson.M{
-			"$or": []bson.M{
-				{"_id": objectID},
-				{"_id": id},
-			},
-		}
-	} else {
-		// Use string ID
-		filter = bson.M{"_id": id}
+
+	filter := userIDFilter(id)
+	filter["deleted_at"] = nil // Skip soft deleted users
+
+	// Empty fields are not stored, so they are matched as missing
+	filter["mfa.enabled"] = previous.Enabled
+	filter["mfa.secret"] = bson.M{"$in": bson.A{previous.Secret, nil}}
+	if previous.Secret != "" {
+		filter["mfa.secret"] = previous.Secret
 	}
-	
-	result, err := collection.DeleteOne(ctx, filter)
+	filter["mfa.last_step"] = bson.M{"$in": bson.A{previous.LastStep, nil}}
+	if previous.LastStep != 0 {
+		filter["mfa.last_step"] = previous.LastStep
+	}
+	filter["mfa.recovery_codes"] = bson.M{"$in": bson.A{bson.A{}, nil}}
+	if len(previous.RecoveryCodes) > 0 {
+		filter["mfa.recovery_codes"] = previous.RecoveryCodes
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa": mfa}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// Delete soft deletes a user in the database
+func (r *MongoRepository) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	filter := userIDFilter(id)
+	filter["deleted_at"] = nil // Skip users that are already deleted
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deleted_at": deletedAt}})
 	if err != nil {
 		return err
 	}
-	
-	if result.DeletedCount == 0 {
+
+	if result.MatchedCount == 0 {
 		return ErrUserNotFound
 	}
-	
+
 	return nil
 }
 
-// List returns all users with pagination
-func (r *MongoRepository) List(ctx context.Context, page, pageSize int) ([]*model.User, error) {
+// Erase permanently removes a user from the database, whether or not they are soft deleted
+func (r *MongoRepository) Erase(ctx context.Context, id string) (bool, error) {
 	collection := r.Client.Database(r.database).Collection(r.collection)
-	
-	// Calculate skip for pagination
-	skip := (page - 1) * pageSize
-	
+
+	result, err := collection.DeleteOne(ctx, userIDFilter(id))
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// Restore undoes the soft deletion of a user in the database
+func (r *MongoRepository) Restore(ctx context.Context, id string) error {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	filter := userIDFilter(id)
+	filter["deleted_at"] = bson.M{"$ne": nil} // Only deleted users can be restored
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"deleted_at": ""}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ListDeleted returns the users soft deleted before the given time
+func (r *MongoRepository) ListDeleted(ctx context.Context, before time.Time) ([]*model.User, error) {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*model.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// userIDFilter matches a user stored with either an ObjectID or a string ID
+func userIDFilter(id string) bson.M {
+	if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+		return bson.M{
+			"$or": []bson.M{
+				{"_id": objectID},
+				{"_id": id},
+			},
+		}
+	}
+	return bson.M{"_id": id}
+}
+
+// List returns a page of the users matching the options with the total number of matches
+func (r *MongoRepository) List(ctx context.Context, opts *model.UserListOptions) ([]*model.User, int64, error) {
+	collection := r.Client.Database(r.database).Collection(r.collection)
+
+	// Build the filter, skipping soft deleted users. Searches match the lowercased
+	// name and email, so that a prefix search can use their indexes.
+	filter := bson.M{"deleted_at": nil}
+	if opts.Search != "" {
+		pattern := regexp.QuoteMeta(strings.ToLower(opts.Search))
+		if opts.Prefix {
+			pattern = "^" + pattern
+		}
+		search := primitive.Regex{Pattern: pattern}
+		filter["$or"] = []bson.M{
+			{"name_lower": search},
+			{"email_lower": search},
+		}
+	}
+	if !opts.CreatedFrom.IsZero() || !opts.CreatedTo.IsZero() {
+		createdAt := bson.M{}
+		if !opts.CreatedFrom.IsZero() {
+			createdAt["$gte"] = opts.CreatedFrom
+		}
+		if !opts.CreatedTo.IsZero() {
+			createdAt["$lt"] = opts.CreatedTo
+		}
+		filter["created_at"] = createdAt
+	}
+
+	// Cursor pages are not counted, as counting would scan every match on each page
+	var total int64
+	if opts.After == nil {
+		var err error
+		total, err = collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Sort by the requested field, breaking ties by ID for stable pages
+	direction := -1
+	if opts.Order == model.SortAsc {
+		direction = 1
+	}
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = model.UserSortCreatedAt
+	}
+
 	// Set up find options
 	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}}) // Sort by created_at desc
-	findOptions.SetSkip(int64(skip))
-	findOptions.SetLimit(int64(pageSize))
-	
+	findOptions.SetSort(bson.D{{Key: string(sortBy), Value: direction}, {Key: "_id", Value: direction}})
+	findOptions.SetLimit(int64(opts.PageSize))
+
+	// Seek past the cursor, which stays fast and stable while users are created,
+	// or skip to the page for offset pagination
+	if opts.After != nil {
+		filter = bson.M{"$and": []bson.M{filter, afterCursorFilter(opts.After, direction < 0)}}
+	} else {
+		findOptions.SetSkip(int64((opts.Page - 1) * opts.PageSize))
+	}
+
 	// Perform find
-	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
-	
+
 	// Decode results
-	var users []*model.User
+	users := []*model.User{}
 	if err := cursor.All(ctx, &users); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	
-	return users, nil
+
+	return users, total, nil
 }
 
 // CountUsers returns the total number of users in the database
 func (r *MongoRepository) CountUsers(ctx context.Context) (int64, error) {
 	collection := r.Client.Database(r.database).Collection(r.collection)
 	
-	count, err := collection.CountDocuments(ctx, bson.M{})
+	count, err := collection.CountDocuments(ctx, bson.M{"deleted_at": nil})
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Session errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// mongoSessionRepository implements the SessionRepository interface
+type mongoSessionRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewMongoSessionRepository creates a new MongoDB repository for login sessions
+func NewMongoSessionRepository(ctx context.Context, client *mongo.Client, dbName string) repository.SessionRepository {
+	repo := &mongoSessionRepository{
+		client:     client,
+		database:   dbName,
+		collection: "sessions",
+	}
+
+	// Create indexes for listings and expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create indexes for listings and expiry
+func (r *mongoSessionRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}},
+		},
+		{
+			// Let MongoDB remove sessions once their refresh tokens have expired
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
+
+// Create stores a new session
+func (r *mongoSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.InsertOne(ctx, session)
+	return err
+}
+
+// GetByID fetches a session by ID
+func (r *mongoSessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	var session model.Session
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListByUser returns every session of a user, newest first
+func (r *mongoSessionRepository) ListByUser(ctx context.Context, userID string) ([]*model.Session, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []*model.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// ListActiveByUser returns the active sessions of a user, most recently seen first
+func (r *mongoSessionRepository) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.Session, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []*model.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Touch records activity on a session, optionally extending its expiry
+func (r *mongoSessionRepository) Touch(ctx context.Context, id, ip string, seenAt, expiresAt time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	set := bson.M{"last_seen_at": seenAt}
+	if ip != "" {
+		set["ip"] = ip
+	}
+	if !expiresAt.IsZero() {
+		set["expires_at"] = expiresAt
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// Revoke revokes a session of a user
+func (r *mongoSessionRepository) Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// Scope by user so that nobody can sign out someone else's session
+	filter := bson.M{
+		"_id":        id,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// RevokeByUser revokes every session of a user
+func (r *mongoSessionRepository) RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// DeleteByUser removes every session of a user
+func (r *mongoSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// mockSessionRepository implements the SessionRepository interface with in-memory storage
+type mockSessionRepository struct {
+	sessions map[string]*model.Session
+	mu       sync.RWMutex
+}
+
+// NewMockSessionRepository creates a new in-memory session repository
+func NewMockSessionRepository() repository.SessionRepository {
+	return &mockSessionRepository{
+		sessions: make(map[string]*model.Session),
+	}
+}
+
+// Create stores a new session
+func (r *mockSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+
+// GetByID fetches a session by ID
+func (r *mockSessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+// ListByUser returns every session of a user, newest first
+func (r *mockSessionRepository) ListByUser(ctx context.Context, userID string) ([]*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := []*model.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+// ListActiveByUser returns the active sessions of a user, most recently seen first
+func (r *mockSessionRepository) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := []*model.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive(now) {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// Touch records activity on a session, optionally extending its expiry
+func (r *mockSessionRepository) Touch(ctx context.Context, id, ip string, seenAt, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	session.LastSeenAt = seenAt
+	if ip != "" {
+		session.IP = ip
+	}
+	if !expiresAt.IsZero() {
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+// Revoke revokes a session of a user
+func (r *mockSessionRepository) Revoke(ctx context.Context, id, userID string, revokedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID || !session.RevokedAt.IsZero() {
+		return false, nil
+	}
+
+	session.RevokedAt = revokedAt
+	return true, nil
+}
+
+// RevokeByUser revokes every session of a user
+func (r *mockSessionRepository) RevokeByUser(ctx context.Context, userID string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt.IsZero() {
+			session.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+// DeleteByUser removes every session of a user
+func (r *mockSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"sort"
+	"sync"
 	"time"
 
 	"backend-challenge/internal/domain/model"
@@ -10,6 +12,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Domain errors
@@ -25,12 +28,42 @@ type mongoTodoRepository struct {
 }
 
 // NewMongoTodoRepository creates a new MongoDB repository for todo items
-func NewMongoTodoRepository(client *mongo.Client, dbName string) repository.TodoRepository {
-	return &mongoTodoRepository{
+func NewMongoTodoRepository(ctx context.Context, client *mongo.Client, dbName string) (repository.TodoRepository, error) {
+	repo := &mongoTodoRepository{
 		client:     client,
 		database:   dbName,
 		collection: "todos",
 	}
+
+	// Create indexes for owner scoped queries and auto-return
+	if err := repo.createIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// todoSort lists items oldest first, like the in-memory repository
+var todoSort = bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+
+// Create indexes for owner scoped queries and auto-return
+func (r *mongoTodoRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "status", Value: 1}, {Key: "return_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			// Finding items to return looks across every owner
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "return_at", Value: 1}},
+		},
+	})
+
+	return err
 }
 
 // Create adds a new todo item
@@ -51,11 +84,11 @@ func (r *mongoTodoRepository) Create(ctx context.Context, todo *model.TodoItem)
 	return err
 }
 
-// GetByID fetches a todo item by ID
-func (r *mongoTodoRepository) GetByID(ctx context.Context, id string) (*model.TodoItem, error) {
+// GetByID fetches a todo item of an owner by ID
+func (r *mongoTodoRepository) GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "owner_id": ownerID}
 
 	var todo model.TodoItem
 	err := collection.FindOne(ctx, filter).Decode(&todo)
@@ -69,40 +102,50 @@ func (r *mongoTodoRepository) GetByID(ctx context.Context, id string) (*model.To
 	return &todo, nil
 }
 
-// Update updates a todo item
-func (r *mongoTodoRepository) Update(ctx context.Context, todo *model.TodoItem) error {
+// Update saves a todo item of its owner if it was not changed since it was read
+func (r *mongoTodoRepository) Update(ctx context.Context, todo *model.TodoItem) (bool, error) {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	// Update the document
-	filter := bson.M{"_id": todo.ID}
+	// Only update the version that was read, so that the update cannot undo a
+	// return or another update that happened in the meantime
+	filter := bson.M{"_id": todo.ID, "owner_id": todo.OwnerID, "version": todo.Version}
+	if todo.Version == 0 {
+		// Items stored before versions were added have none
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"type":       todo.Type,
-			"name":       todo.Name,
-			"status":     todo.Status,
-			"clicked_at": todo.ClickedAt,
-			"return_at":  todo.ReturnAt,
-			"updated_at": time.Now(),
+			"type":         todo.Type,
+			"name":         todo.Name,
+			"status":       todo.Status,
+			"return_after": todo.ReturnAfter,
+			"clicked_at":   todo.ClickedAt,
+			"return_at":    todo.ReturnAt,
+			"returned_at":  todo.ReturnedAt,
+			"updated_at":   now,
+			"version":      todo.Version + 1,
 		},
 	}
 
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return false, err
 	}
-
 	if result.MatchedCount == 0 {
-		return ErrTodoNotFound
+		return false, nil
 	}
 
-	return nil
+	todo.UpdatedAt = now
+	todo.Version++
+	return true, nil
 }
 
-// Delete removes a todo item
-func (r *mongoTodoRepository) Delete(ctx context.Context, id string) error {
+// Delete removes a todo item of an owner
+func (r *mongoTodoRepository) Delete(ctx context.Context, ownerID, id string) error {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "owner_id": ownerID}
 
 	result, err := collection.DeleteOne(ctx, filter)
 	if err != nil {
@@ -116,15 +159,21 @@ func (r *mongoTodoRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// List returns all todo items
-func (r *mongoTodoRepository) List(ctx context.Context) ([]*model.TodoItem, error) {
+// DeleteByOwner removes every todo item of an owner
+func (r *mongoTodoRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	// Empty filter to get all documents
-	filter := bson.M{}
+	_, err := collection.DeleteMany(ctx, bson.M{"owner_id": ownerID})
+	return err
+}
 
-	// Set up find options to sort by creation date
-	cursor, err := collection.Find(ctx, filter)
+// List returns all todo items of an owner
+func (r *mongoTodoRepository) List(ctx context.Context, ownerID string) ([]*model.TodoItem, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	filter := bson.M{"owner_id": ownerID}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(todoSort))
 	if err != nil {
 		return nil, err
 	}
@@ -138,13 +187,16 @@ func (r *mongoTodoRepository) List(ctx context.Context) ([]*model.TodoItem, erro
 	return todos, nil
 }
 
-// FindByStatus returns all todo items with a specific status
-func (r *mongoTodoRepository) FindByStatus(ctx context.Context, status model.ItemStatus) ([]*model.TodoItem, error) {
+// FindByStatus returns all todo items of an owner with a specific status
+func (r *mongoTodoRepository) FindByStatus(ctx context.Context, ownerID string, status model.ItemStatus) ([]*model.TodoItem, error) {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	filter := bson.M{"status": status}
+	filter := bson.M{
+		"owner_id": ownerID,
+		"status":   status,
+	}
 
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(todoSort))
 	if err != nil {
 		return nil, err
 	}
@@ -158,16 +210,17 @@ func (r *mongoTodoRepository) FindByStatus(ctx context.Context, status model.Ite
 	return todos, nil
 }
 
-// FindByTypeAndStatus returns all todo items with a specific type and status
-func (r *mongoTodoRepository) FindByTypeAndStatus(ctx context.Context, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error) {
+// FindByTypeAndStatus returns all todo items of an owner with a specific type and status
+func (r *mongoTodoRepository) FindByTypeAndStatus(ctx context.Context, ownerID string, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error) {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
 	filter := bson.M{
-		"type":   itemType,
-		"status": status,
+		"owner_id": ownerID,
+		"type":     itemType,
+		"status":   status,
 	}
 
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(todoSort))
 	if err != nil {
 		return nil, err
 	}
@@ -181,16 +234,17 @@ func (r *mongoTodoRepository) FindByTypeAndStatus(ctx context.Context, itemType
 	return todos, nil
 }
 
-// UpdateStatus updates the status of a todo item
-func (r *mongoTodoRepository) UpdateStatus(ctx context.Context, id string, status model.ItemStatus) error {
+// UpdateStatus updates the status of a todo item of an owner
+func (r *mongoTodoRepository) UpdateStatus(ctx context.Context, ownerID, id string, status model.ItemStatus) error {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "owner_id": ownerID}
 	update := bson.M{
 		"$set": bson.M{
 			"status":     status,
 			"updated_at": time.Now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
 	result, err := collection.UpdateOne(ctx, filter, update)
@@ -205,32 +259,225 @@ func (r *mongoTodoRepository) UpdateStatus(ctx context.Context, id string, statu
 	return nil
 }
 
-// FindToReturn finds all todo items that should be returned to the main list
-func (r *mongoTodoRepository) FindToReturn(ctx context.Context, currentTime string) ([]*model.TodoItem, error) {
+// FindScheduledReturns returns the todo items of every owner waiting in their type column
+func (r *mongoTodoRepository) FindScheduledReturns(ctx context.Context) ([]*model.TodoItem, error) {
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	// Parse the current time
-	now, err := time.Parse(time.RFC3339, currentTime)
+	// Only the fields needed to schedule the returns
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "owner_id": 1, "status": 1, "return_at": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"status": model.StatusColumn}, opts)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	var todos []*model.TodoItem
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
 
-	// Find items that should return to main list
+// ReturnIfDue moves a todo item back to the main list if it is still in its type column and due
+func (r *mongoTodoRepository) ReturnIfDue(ctx context.Context, id string, now time.Time) (bool, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// The conditions make the return safe against clicks and other instances
+	// returning the item at the same time
 	filter := bson.M{
+		"_id":       id,
 		"status":    model.StatusColumn,
 		"return_at": bson.M{"$lte": now},
 	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      model.StatusMain,
+			"returned_at": now,
+			"updated_at":  now,
+		},
+		"$inc": bson.M{"version": 1},
+	}
 
-	cursor, err := collection.Find(ctx, filter)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	defer cursor.Close(ctx)
 
-	var todos []*model.TodoItem
-	if err := cursor.All(ctx, &todos); err != nil {
-		return nil, err
+	return result.ModifiedCount > 0, nil
+}
+
+// mockTodoRepository implements the TodoRepository interface with in-memory storage
+type mockTodoRepository struct {
+	todos map[string]*model.TodoItem
+	mu    sync.RWMutex
+}
+
+// NewMockTodoRepository creates a new in-memory todo repository
+func NewMockTodoRepository() repository.TodoRepository {
+	return &mockTodoRepository{
+		todos: make(map[string]*model.TodoItem),
 	}
+}
 
-	return todos, nil
-}
\ No newline at end of file
+// Create adds a new todo item
+func (r *mockTodoRepository) Create(ctx context.Context, todo *model.TodoItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if todo.CreatedAt.IsZero() {
+		todo.CreatedAt = now
+	}
+	if todo.UpdatedAt.IsZero() {
+		todo.UpdatedAt = now
+	}
+
+	copied := *todo
+	r.todos[todo.ID] = &copied
+	return nil
+}
+
+// GetByID fetches a todo item of an owner by ID
+func (r *mockTodoRepository) GetByID(ctx context.Context, ownerID, id string) (*model.TodoItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, ok := r.todos[id]
+	if !ok || todo.OwnerID != ownerID {
+		return nil, ErrTodoNotFound
+	}
+
+	copied := *todo
+	return &copied, nil
+}
+
+// Update saves a todo item of its owner if it was not changed since it was read
+func (r *mockTodoRepository) Update(ctx context.Context, todo *model.TodoItem) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.todos[todo.ID]
+	if !ok || existing.OwnerID != todo.OwnerID || existing.Version != todo.Version {
+		return false, nil
+	}
+
+	todo.UpdatedAt = time.Now()
+	todo.Version++
+	copied := *todo
+	r.todos[todo.ID] = &copied
+	return true, nil
+}
+
+// Delete removes a todo item of an owner
+func (r *mockTodoRepository) Delete(ctx context.Context, ownerID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || todo.OwnerID != ownerID {
+		return ErrTodoNotFound
+	}
+
+	delete(r.todos, id)
+	return nil
+}
+
+// DeleteByOwner removes every todo item of an owner
+func (r *mockTodoRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, todo := range r.todos {
+		if todo.OwnerID == ownerID {
+			delete(r.todos, id)
+		}
+	}
+	return nil
+}
+
+// List returns all todo items of an owner
+func (r *mockTodoRepository) List(ctx context.Context, ownerID string) ([]*model.TodoItem, error) {
+	return r.find(func(todo *model.TodoItem) bool {
+		return todo.OwnerID == ownerID
+	}), nil
+}
+
+// FindByStatus returns all todo items of an owner with a specific status
+func (r *mockTodoRepository) FindByStatus(ctx context.Context, ownerID string, status model.ItemStatus) ([]*model.TodoItem, error) {
+	return r.find(func(todo *model.TodoItem) bool {
+		return todo.OwnerID == ownerID && todo.Status == status
+	}), nil
+}
+
+// FindByTypeAndStatus returns all todo items of an owner with a specific type and status
+func (r *mockTodoRepository) FindByTypeAndStatus(ctx context.Context, ownerID string, itemType model.ItemType, status model.ItemStatus) ([]*model.TodoItem, error) {
+	return r.find(func(todo *model.TodoItem) bool {
+		return todo.OwnerID == ownerID && todo.Type == itemType && todo.Status == status
+	}), nil
+}
+
+// UpdateStatus updates the status of a todo item of an owner
+func (r *mockTodoRepository) UpdateStatus(ctx context.Context, ownerID, id string, status model.ItemStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || todo.OwnerID != ownerID {
+		return ErrTodoNotFound
+	}
+
+	todo.Status = status
+	todo.UpdatedAt = time.Now()
+	todo.Version++
+	return nil
+}
+
+// FindScheduledReturns returns the todo items of every owner waiting in their type column
+func (r *mockTodoRepository) FindScheduledReturns(ctx context.Context) ([]*model.TodoItem, error) {
+	return r.find(func(todo *model.TodoItem) bool {
+		return todo.Status == model.StatusColumn
+	}), nil
+}
+
+// ReturnIfDue moves a todo item back to the main list if it is still in its type column and due
+func (r *mockTodoRepository) ReturnIfDue(ctx context.Context, id string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || todo.Status != model.StatusColumn || todo.ReturnAt.After(now) {
+		return false, nil
+	}
+
+	todo.Status = model.StatusMain
+	todo.ReturnedAt = now
+	todo.UpdatedAt = now
+	todo.Version++
+	return true, nil
+}
+
+// find returns copies of the todo items matching a condition, oldest first
+func (r *mockTodoRepository) find(match func(*model.TodoItem) bool) []*model.TodoItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := []*model.TodoItem{}
+	for _, todo := range r.todos {
+		if match(todo) {
+			copied := *todo
+			todos = append(todos, &copied)
+		}
+	}
+
+	// Break ties by ID like todoSort, which compares the string IDs the same way
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].CreatedAt.Equal(todos[j].CreatedAt) {
+			return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+		}
+		return todos[i].ID < todos[j].ID
+	})
+
+	return todos
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend-challenge/internal/domain/model"
+	"backend-challenge/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoTokenRevocationRepository implements the TokenRevocationRepository interface
+type mongoTokenRevocationRepository struct {
+	client              *mongo.Client
+	database            string
+	tokenCollection     string
+	userTokenCollection string
+}
+
+// NewMongoTokenRevocationRepository creates a new MongoDB repository for revoked tokens
+func NewMongoTokenRevocationRepository(ctx context.Context, client *mongo.Client, dbName string) repository.TokenRevocationRepository {
+	repo := &mongoTokenRevocationRepository{
+		client:              client,
+		database:            dbName,
+		tokenCollection:     "revoked_tokens",
+		userTokenCollection: "user_token_revocations",
+	}
+
+	// Create index for expiry
+	repo.createIndexes(ctx)
+
+	return repo
+}
+
+// Create index for expiry
+func (r *mongoTokenRevocationRepository) createIndexes(ctx context.Context) error {
+	collection := r.client.Database(r.database).Collection(r.tokenCollection)
+
+	// Revoked tokens can be dropped once they would have expired anyway
+	_, err := collection.Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	)
+
+	return err
+}
+
+// RevokeToken adds a single token to the revocation list
+func (r *mongoTokenRevocationRepository) RevokeToken(ctx context.Context, token *model.RevokedToken) error {
+	collection := r.client.Database(r.database).Collection(r.tokenCollection)
+
+	// Set revocation time if not set
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = time.Now()
+	}
+
+	// Revoking the same token twice is not an error
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": token.ID}, token, options.Replace().SetUpsert(true))
+	return err
+}
+
+// IsTokenRevoked checks whether a token ID is on the revocation list
+func (r *mongoTokenRevocationRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	collection := r.client.Database(r.database).Collection(r.tokenCollection)
+
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": tokenID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// RevokeUserTokens revokes every token of a user issued at or before the given time
+func (r *mongoTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, revokedBefore time.Time) error {
+	collection := r.client.Database(r.database).Collection(r.userTokenCollection)
+
+	// Never move an existing revocation backwards
+	filter := bson.M{"_id": userID}
+	update := bson.M{
+		"$max": bson.M{"revoked_before": revokedBefore},
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetUserRevocation returns the time before which the user's tokens are revoked
+func (r *mongoTokenRevocationRepository) GetUserRevocation(ctx context.Context, userID string) (time.Time, error) {
+	collection := r.client.Database(r.database).Collection(r.userTokenCollection)
+
+	var revocation model.UserTokenRevocation
+	err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&revocation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return revocation.RevokedBefore, nil
+}
+
+// mockTokenRevocationRepository implements the TokenRevocationRepository interface with in-memory storage
+type mockTokenRevocationRepository struct {
+	tokens map[string]*model.RevokedToken
+	users  map[string]time.Time
+	mu     sync.RWMutex
+}
+
+// NewMockTokenRevocationRepository creates a new in-memory revocation repository
+func NewMockTokenRevocationRepository() repository.TokenRevocationRepository {
+	return &mockTokenRevocationRepository{
+		tokens: make(map[string]*model.RevokedToken),
+		users:  make(map[string]time.Time),
+	}
+}
+
+// RevokeToken adds a single token to the revocation list
+func (r *mockTokenRevocationRepository) RevokeToken(ctx context.Context, token *model.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Set revocation time if not set
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = time.Now()
+	}
+
+	// Drop entries for tokens that have expired in the meantime
+	now := time.Now()
+	for id, revoked := range r.tokens {
+		if now.After(revoked.ExpiresAt) {
+			delete(r.tokens, id)
+		}
+	}
+
+	r.tokens[token.ID] = token
+	return nil
+}
+
+// IsTokenRevoked checks whether a token ID is on the revocation list
+func (r *mockTokenRevocationRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.tokens[tokenID]
+	return ok, nil
+}
+
+// RevokeUserTokens revokes every token of a user issued at or before the given time
+func (r *mockTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, revokedBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Never move an existing revocation backwards
+	if revokedBefore.After(r.users[userID]) {
+		r.users[userID] = revokedBefore
+	}
+	return nil
+}
+
+// GetUserRevocation returns the time before which the user's tokens are revoked
+func (r *mockTokenRevocationRepository) GetUserRevocation(ctx context.Context, userID string) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.users[userID], nil
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"backend-challenge/internal/domain/service"
+)
+
+func TestFileBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Test case: objects are stored under nested keys and replaced
+	if err := store.Put(ctx, "avatars/user-1/a.png", []byte("first")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Put(ctx, "avatars/user-1/a.png", []byte("second")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := store.Get(ctx, "avatars/user-1/a.png")
+	if err != nil || !bytes.Equal(data, []byte("second")) {
+		t.Errorf("Expected replaced object, got %q, %v", data, err)
+	}
+
+	// Test case: deleted and missing objects are not found
+	if err := store.Delete(ctx, "avatars/user-1/a.png"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Delete(ctx, "avatars/user-1/a.png"); err != nil {
+		t.Errorf("Expected deleting a missing object to succeed, got %v", err)
+	}
+	if _, err := store.Get(ctx, "avatars/user-1/a.png"); err != service.ErrBlobNotFound {
+		t.Errorf("Expected error %v, got %v", service.ErrBlobNotFound, err)
+	}
+
+	// Test case: keys cannot point outside the directory
+	for _, key := range []string{"", "../escape", "/etc/passwd", "a/../../b", `a\b`} {
+		if err := store.Put(ctx, key, []byte("x")); err != ErrInvalidBlobKey {
+			t.Errorf("Expected error %v for key %q, got %v", ErrInvalidBlobKey, key, err)
+		}
+	}
+}
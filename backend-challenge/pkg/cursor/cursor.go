@@ -0,0 +1,54 @@
+// Package cursor implements opaque cursors for keyset pagination over items
+// ordered by creation time and ID.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Cursor is the sort key of the last item of a page. The next page starts
+// with the first item after it.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"` // Breaks ties between items created at the same time
+}
+
+// New returns the cursor pointing after an item
+func New(createdAt time.Time, id string) Cursor {
+	return Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// Encode returns the opaque, URL-safe form of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor returned by Encode
+func Decode(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.CreatedAt.IsZero() || c.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Precedes reports whether the item with the given key comes after the cursor,
+// and so belongs to the next page, in ascending or descending order
+func (c Cursor) Precedes(createdAt time.Time, id string, desc bool) bool {
+	if desc {
+		return createdAt.Before(c.CreatedAt) || (createdAt.Equal(c.CreatedAt) && id < c.ID)
+	}
+	return createdAt.After(c.CreatedAt) || (createdAt.Equal(c.CreatedAt) && id > c.ID)
+}
@@ -0,0 +1,53 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	c := New(time.Date(2024, 5, 1, 12, 30, 0, 123000000, time.UTC), "6632a1f0c2a4b1e9d0f1a2b3")
+
+	decoded, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID {
+		t.Errorf("Expected %+v, got %+v", c, decoded)
+	}
+
+	for _, invalid := range []string{"", "not a cursor!", "e30", New(time.Time{}, "id").Encode()} {
+		if _, err := Decode(invalid); err != ErrInvalidCursor {
+			t.Errorf("Expected error %v for %q, got %v", ErrInvalidCursor, invalid, err)
+		}
+	}
+}
+
+func TestPrecedes(t *testing.T) {
+	now := time.Now()
+	c := New(now, "b")
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		id        string
+		desc      bool
+		want      bool
+	}{
+		{"older item, descending", now.Add(-time.Second), "z", true, true},
+		{"newer item, descending", now.Add(time.Second), "a", true, false},
+		{"same time, lower ID, descending", now, "a", true, true},
+		{"same item, descending", now, "b", true, false},
+		{"newer item, ascending", now.Add(time.Second), "a", false, true},
+		{"same time, higher ID, ascending", now, "c", false, true},
+		{"same item, ascending", now, "b", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Precedes(tt.createdAt, tt.id, tt.desc); got != tt.want {
+				t.Errorf("Precedes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
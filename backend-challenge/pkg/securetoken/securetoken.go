@@ -0,0 +1,31 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// DefaultSize is the number of random bytes used for generated tokens
+const DefaultSize = 32
+
+// New generates a URL-safe random token from size random bytes
+func New(size int) (string, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash returns the hex encoded SHA-256 digest of a token, suitable for storage
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// Square crops the center square of src and scales it to size by size pixels.
+// Each pixel is the average of the source pixels it covers, so downscaling
+// does not alias.
+func Square(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+
+	for y := 0; y < size; y++ {
+		sy0, sy1 := span(y0, y, side, size)
+		for x := 0; x < size; x++ {
+			sx0, sx1 := span(x0, x, side, size)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// span returns the source pixel range covered by destination pixel i,
+// at least one pixel wide when upscaling
+func span(origin, i, side, size int) (int, int) {
+	start := origin + i*side/size
+	end := origin + (i+1)*side/size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
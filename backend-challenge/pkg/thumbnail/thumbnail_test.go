@@ -0,0 +1,46 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSquare(t *testing.T) {
+	// A wide image with red borders around a 2x2 checkerboard of black and white
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	red := color.RGBA{R: 255, A: 255}
+	src.Set(0, 0, red)
+	src.Set(0, 1, red)
+	src.Set(3, 0, red)
+	src.Set(3, 1, red)
+	src.Set(1, 0, color.White)
+	src.Set(2, 0, color.Black)
+	src.Set(1, 1, color.Black)
+	src.Set(2, 1, color.White)
+
+	// Test case: downscaling the center square averages the checkerboard to gray
+	thumb := Square(src, 1)
+	if thumb.Bounds() != image.Rect(0, 0, 1, 1) {
+		t.Fatalf("Expected 1x1 thumbnail, got %v", thumb.Bounds())
+	}
+	got := thumb.RGBAAt(0, 0)
+	if got.R != got.G || got.G != got.B || got.R < 126 || got.R > 128 || got.A != 255 {
+		t.Errorf("Expected gray without the red borders, got %v", got)
+	}
+
+	// Test case: upscaling repeats source pixels
+	thumb = Square(src, 4)
+	if thumb.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("Expected 4x4 thumbnail, got %v", thumb.Bounds())
+	}
+	if thumb.RGBAAt(0, 0) != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) || thumb.RGBAAt(3, 0) != (color.RGBA{A: 255}) {
+		t.Errorf("Expected the checkerboard, got %v and %v", thumb.RGBAAt(0, 0), thumb.RGBAAt(3, 0))
+	}
+
+	// Test case: empty images give a transparent thumbnail
+	thumb = Square(image.NewRGBA(image.Rect(0, 0, 0, 10)), 2)
+	if thumb.RGBAAt(1, 1).A != 0 {
+		t.Errorf("Expected transparent thumbnail, got %v", thumb.RGBAAt(1, 1))
+	}
+}
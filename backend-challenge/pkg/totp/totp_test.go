@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// RFC 6238 test vectors for SHA-1, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("Expected code %s at %d, got %s", tt.code, tt.unix, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	now := time.Now()
+	code, _ := Code(secret, Step(now)-1)
+
+	// Test case: previous step is accepted with skew
+	step, ok := Validate(secret, code, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Expected code to match previous step")
+	}
+
+	// Test case: previous step is rejected without skew
+	if _, ok := Validate(secret, code, now, 0); ok {
+		t.Errorf("Expected code to be rejected without skew")
+	}
+
+	// Test case: malformed code
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Errorf("Expected malformed code to be rejected")
+	}
+
+	// Test URI
+	uri := URI("Example", "alice@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Example:alice@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+}
@@ -114,15 +114,15 @@ fi
 # 4. Test User Management
 print_header "4. User Management Tests"
 
-# 4.1 List Users
+# 4.1 List Users (requires the admin role)
 print_info "4.1 List Users"
 USER_LIST_RESPONSE=$(curl -s -X GET \
   "${REST_URL}/api/users" \
   -H "Authorization: Bearer ${TOKEN}")
 echo "$USER_LIST_RESPONSE" | jq .
 
-# Get user ID for other tests
-USER_ID=$(echo "$USER_LIST_RESPONSE" | jq -r '.data[0].id // empty')
+# Get user ID for other tests (regular users can only access themselves)
+USER_ID=$(echo "$LOGIN_RESPONSE" | jq -r '.user.id // empty')
 
 # 4.2 Get User by ID
 if [[ ! -z "$USER_ID" ]]; then